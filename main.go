@@ -5,48 +5,267 @@ import (
 	"fmt"
 	"go/scanner"
 	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
 
 	"github.com/daixiang0/gci/pkg/gci"
 )
 
-var (
-	doWrite = flag.Bool("w", false, "doWrite result to (source) file instead of stdout")
-	doDiff  = flag.Bool("d", false, "display diffs instead of rewriting files")
+// version is set with -ldflags "-X main.version=..."
+var version = ""
 
-	localFlag string
-
-	exitCode = 0
-)
+var exitCode = 0
 
 func report(err error) {
 	if err == nil {
 		return
 	}
+	if list, ok := err.(gci.ErrorList); ok {
+		for _, err := range list {
+			report(err)
+		}
+		return
+	}
 	scanner.PrintError(os.Stderr, err)
 	exitCode = 1
 }
 
-func parseFlags() []string {
-	flag.StringVar(&localFlag, "local", "", "put imports beginning with this string after 3rd-party packages, only support one string")
+// stringsFlag is a repeatable flag, the first value replaces the default ones
+type stringsFlag struct {
+	values []string
+	isSet  bool
+}
+
+func newStringsFlag(defaults []string) *stringsFlag {
+	return &stringsFlag{values: defaults}
+}
+
+func (f *stringsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.values, ",")
+}
+
+func (f *stringsFlag) Set(v string) error {
+	if !f.isSet {
+		f.values = nil
+		f.isSet = true
+	}
+	// allow to reset the defaults with an empty value
+	if v != "" {
+		f.values = append(f.values, v)
+	}
+	return nil
+}
+
+// headersFlag is a repeatable flag of section headers in the form name=comment
+type headersFlag map[string]string
+
+func (f headersFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for name, header := range f {
+		pairs = append(pairs, name+"="+header)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f headersFlag) Set(v string) error {
+	i := strings.Index(v, "=")
+	if i < 0 {
+		return fmt.Errorf("%q is not in the form name=comment", v)
+	}
+	name := v[:i]
+	if !gci.IsSectionName(name) {
+		return fmt.Errorf("unknown section %q, use standard, default or local", name)
+	}
+	f[name] = v[i+1:]
+	return nil
+}
+
+// sectionFlags are shared by all commands
+type sectionFlags struct {
+	localFlag string
+	format    string
+	jobs      int
 
-	flag.Parse()
-	return flag.Args()
+	skipDirs, skipFiles *stringsFlag
+	noIgnore            bool
+	changedSince        string
+	staged              bool
+	includeGenerated    bool
+	followSymlinks      bool
+	preserveMtime       bool
+	backup              bool
+	verifyIdempotent    bool
+	gofmt               bool
+	sectionHeaders      headersFlag
+	prune               bool
+	addMissing          bool
+	removeAliases       bool
 }
 
-func usage() {
-	_, _ = fmt.Fprintf(os.Stderr, "usage: gci [flags] [path ...]\n")
-	flag.PrintDefaults()
-	os.Exit(2)
+func (f *sectionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.localFlag, "local", "", "put imports beginning with this string after 3rd-party packages, only support one string")
+	fs.StringVar(&f.format, "format", "text", "output format: text, json, sarif, checkstyle, junit or github")
+	fs.IntVar(&f.jobs, "j", runtime.GOMAXPROCS(0), "number of files to process concurrently")
+
+	f.skipDirs = newStringsFlag(gci.DefaultSkipDirs)
+	f.skipFiles = newStringsFlag(nil)
+	fs.Var(f.skipDirs, "skip-dir", "skip directories matching this glob pattern, can be repeated, an empty value disables the defaults")
+	fs.Var(f.skipFiles, "skip", "skip files matching this glob pattern, can be repeated")
+	fs.BoolVar(&f.noIgnore, "no-ignore", false, "don't skip files matched by .gitignore and .gciignore files")
+	fs.StringVar(&f.changedSince, "changed-since", "", "process only files that differ from this git revision")
+	fs.BoolVar(&f.staged, "staged", false, "process only files staged in git")
+	fs.BoolVar(&f.includeGenerated, "include-generated", false, "process files with the \"Code generated ... DO NOT EDIT.\" comment")
+	fs.BoolVar(&f.followSymlinks, "follow-symlinks", false, "rewrite targets of symlinks instead of refusing to write them")
+	fs.BoolVar(&f.preserveMtime, "preserve-mtime", false, "keep the modification time of rewritten files")
+	fs.BoolVar(&f.backup, "backup", false, "store originals of rewritten files in "+gci.BackupDir+", they can be restored with undo")
+	fs.BoolVar(&f.gofmt, "gofmt", false, "format results with gofmt if files are already gofmt-clean")
+	fs.BoolVar(&f.prune, "prune", false, "remove unused imports, packages are type-checked from source without network access")
+	fs.BoolVar(&f.addMissing, "add-missing", false, "add imports of referenced packages found in the standard library, the module and its dependencies")
+	fs.BoolVar(&f.removeAliases, "remove-redundant-aliases", false, "remove names of imports that are the same as names of the packages")
+	f.sectionHeaders = make(headersFlag)
+	fs.Var(f.sectionHeaders, "section-header", "put the comment before imports of the section in the form name=comment, can be repeated")
+	fs.BoolVar(&f.verifyIdempotent, "verify-idempotent", false, "format files twice and report files changed by the second pass with diffs of both passes")
+}
+
+func (f *sectionFlags) flagSet(doWrite, doDiff bool) *gci.FlagSet {
+	return &gci.FlagSet{
+		LocalFlag: f.localFlag,
+		DoWrite:   &doWrite,
+		DoDiff:    &doDiff,
+		Jobs:      f.jobs,
+		SkipDirs:  f.skipDirs.values,
+		SkipFiles: f.skipFiles.values,
+		NoIgnore:  f.noIgnore,
+
+		ChangedSince: f.changedSince,
+		Staged:       f.staged,
+
+		IncludeGenerated: f.includeGenerated,
+		FollowSymlinks:   f.followSymlinks,
+		PreserveMtime:    f.preserveMtime,
+		VerifyIdempotent: f.verifyIdempotent,
+		Gofmt:            f.gofmt,
+		SectionHeaders:   f.sectionHeaders,
+		Prune:            f.prune,
+		AddMissing:       f.addMissing,
+
+		RemoveRedundantAliases: f.removeAliases,
+	}
+}
+
+type command struct {
+	name, usage string
+	// doWrite and doDiff are passed to gci.FlagSet
+	doWrite, doDiff bool
+	// reporter replaces the text reporter
+	reporter func() gci.Reporter
+	// run replaces processing of files
+	run func(paths []string, set *gci.FlagSet)
+}
+
+var commands = []*command{
+	{name: "print", usage: "print formatted files"},
+	{name: "write", usage: "write result to (source) files", doWrite: true},
+	{name: "diff", usage: "display diffs instead of rewriting files", doDiff: true},
+	{name: "list", usage: "list files whose imports are not formatted", reporter: gci.NewListReporter},
+	{name: "check", usage: "report violations and exit with non-zero status if imports are not formatted", reporter: gci.NewCheckReporter},
+	{name: "explain", usage: "explain how imports of files are assigned to sections", run: explainFiles},
+	{name: "undo", usage: "restore files rewritten by the last run with -backup", run: undo},
+	{name: "version", usage: "print the version", run: printVersion},
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func usage(fs *flag.FlagSet) func() {
+	return func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "usage: gci <command> [flags] [path ...]\n       gci [flags] [path ...]\n\ncommands:\n")
+		for _, c := range commands {
+			fmt.Fprintf(out, "  %-8s %s\n", c.name, c.usage)
+		}
+		fmt.Fprintf(out, "\nflags:\n")
+		fs.PrintDefaults()
+		os.Exit(2)
+	}
 }
 
 func main() {
-	flag.Usage = usage
-	paths := parseFlags()
+	var (
+		cmd   *command
+		flags sectionFlags
+	)
+	// a path named as a command is processed in the legacy form
+	if len(os.Args) > 1 && !exists(os.Args[1]) {
+		cmd = findCommand(os.Args[1])
+	}
+
+	if cmd == nil {
+		// the legacy form: the command is chosen by flags
+		doWrite := flag.Bool("w", false, "write result to (source) file instead of stdout")
+		doDiff := flag.Bool("d", false, "display diffs instead of rewriting files")
+		explain := flag.Bool("explain", false, "explain how imports of files are assigned to sections")
+		flags.register(flag.CommandLine)
+		flag.Usage = usage(flag.CommandLine)
+		flag.Parse()
 
-	flagSet := &gci.FlagSet{
-		LocalFlag: localFlag,
-		DoWrite:   doWrite,
-		DoDiff:    doDiff,
+		cmd = &command{doWrite: *doWrite, doDiff: *doDiff}
+		if *explain {
+			cmd.run = explainFiles
+		}
+		run(cmd, &flags, flag.Args())
+		return
+	}
+
+	fs := flag.NewFlagSet("gci "+cmd.name, flag.ExitOnError)
+	flags.register(fs)
+	fs.Usage = usage(fs)
+	_ = fs.Parse(os.Args[2:])
+	run(cmd, &flags, fs.Args())
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func run(cmd *command, flags *sectionFlags, paths []string) {
+	flagSet := flags.flagSet(cmd.doWrite, cmd.doDiff)
+
+	if cmd.run != nil {
+		cmd.run(paths, flagSet)
+		os.Exit(exitCode)
+	}
+
+	reporter, err := gci.NewReporter(flags.format, flagSet)
+	if err != nil {
+		report(err)
+		os.Exit(exitCode)
+	}
+	if cmd.reporter != nil && (flags.format == "" || flags.format == "text") {
+		reporter = cmd.reporter()
+	}
+	summary := &gci.Summary{}
+	flagSet.Reporter = reporter
+	flagSet.Summary = summary
+
+	if flags.backup && cmd.doWrite {
+		if flagSet.Backup, err = gci.NewBackup(gci.BackupDir); err != nil {
+			report(err)
+			os.Exit(exitCode)
+		}
 	}
 
 	for _, path := range paths {
@@ -61,5 +280,41 @@ func main() {
 			}
 		}
 	}
+	report(reporter.Finish(os.Stdout))
+	if flagSet.Backup != nil {
+		report(flagSet.Backup.Close())
+	}
+
+	if cmd.name == "check" && summary.Changed > 0 {
+		exitCode = 1
+	}
 	os.Exit(exitCode)
 }
+
+func explainFiles(paths []string, set *gci.FlagSet) {
+	for _, path := range paths {
+		switch dir, err := os.Stat(path); {
+		case err != nil:
+			report(err)
+		case dir.IsDir():
+			report(gci.ExplainDir(path, os.Stdout, set))
+		default:
+			report(gci.Explain(path, os.Stdout, set))
+		}
+	}
+}
+
+func undo([]string, *gci.FlagSet) {
+	report(gci.Undo(gci.BackupDir, os.Stdout))
+}
+
+func printVersion([]string, *gci.FlagSet) {
+	v := version
+	if v == "" {
+		v = "(devel)"
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+			v = info.Main.Version
+		}
+	}
+	fmt.Printf("gci version %s\n", v)
+}
@@ -1,12 +1,71 @@
 package analyzer
 
 import (
+	"go/token"
+	"strings"
+
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
+
+	"github.com/daixiang0/gci/pkg/gci"
+)
+
+var (
+	localFlag              string
+	removeRedundantAliases bool
 )
 
 var Analyzer = &analysis.Analyzer{
 	Name:     "gci",
 	Doc:      "A tool that control golang package import order and make it always deterministic.",
 	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func init() {
+	Analyzer.Flags.StringVar(&localFlag, "local", "", "put imports beginning with this string after 3rd-party packages, only support one string")
+	Analyzer.Flags.BoolVar(&removeRedundantAliases, "remove-redundant-aliases", false, "report names of imports that are the same as names of the packages")
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	set := &gci.FlagSet{LocalFlag: localFlag, RemoveRedundantAliases: removeRedundantAliases}
+
+	for _, f := range pass.Files {
+		file := pass.Fset.File(f.Pos())
+		// skip files generated by cgo
+		if !strings.HasSuffix(file.Name(), ".go") {
+			continue
+		}
+
+		violations, fix, err := gci.Lint(file.Name(), set)
+		if err != nil {
+			// the error is reported for the file, other files of the
+			// package are still analyzed
+			pass.Reportf(f.Package, "%v", err)
+			continue
+		}
+
+		for i, v := range violations {
+			pos := file.LineStart(v.Line) + token.Pos(v.Column-1)
+			d := analysis.Diagnostic{
+				Pos:      pos,
+				End:      file.LineStart(v.Line) + token.Pos(v.EndColumn-1),
+				Category: string(v.Kind),
+				Message:  v.Message,
+			}
+			// all diagnostics share the same fix, so it is suggested only once
+			if i == 0 && fix != nil {
+				d.SuggestedFixes = []analysis.SuggestedFix{{
+					Message: "Reorder imports",
+					TextEdits: []analysis.TextEdit{{
+						Pos:     file.LineStart(fix.StartLine),
+						End:     file.LineStart(fix.EndLine),
+						NewText: []byte(fix.Text),
+					}},
+				}}
+			}
+			pass.Report(d)
+		}
+	}
+	return nil, nil
 }
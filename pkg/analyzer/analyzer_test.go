@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+)
+
+type diagnostic struct {
+	Pos      string
+	Category string
+	Message  string
+}
+
+// analyze runs the analyzer on the file
+func analyze(t *testing.T, filename string) (*token.FileSet, []analysis.Diagnostic, []diagnostic) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	require.Nil(t, err)
+
+	var diagnostics []analysis.Diagnostic
+	pass := &analysis.Pass{
+		Analyzer: Analyzer,
+		Fset:     fset,
+		Files:    []*ast.File{f},
+		Report: func(d analysis.Diagnostic) {
+			diagnostics = append(diagnostics, d)
+		},
+	}
+	_, err = Analyzer.Run(pass)
+	require.Nil(t, err)
+
+	var got []diagnostic
+	for _, d := range diagnostics {
+		got = append(got, diagnostic{
+			Pos:      fset.Position(d.Pos).String(),
+			Category: d.Category,
+			Message:  d.Message,
+		})
+	}
+	return fset, diagnostics, got
+}
+
+func TestAnalyzer(t *testing.T) {
+	require := require.New(t)
+
+	fset, diagnostics, got := analyze(t, "testdata/a.go")
+	require.Equal([]diagnostic{
+		{Pos: "testdata/a.go:5:2", Category: "wrong-order", Message: `"fmt" should come before "os"`},
+		{Pos: "testdata/a.go:6:2", Category: "wrong-section", Message: `"github.com/owner/repo" belongs in section "default" but is in "standard"`},
+		{Pos: "testdata/a.go:6:2", Category: "missing-blank-line", Message: `missing blank line between sections "standard" and "default"`},
+		{Pos: "testdata/a.go:8:2", Category: "unexpected-blank-line", Message: `unexpected blank line inside section "standard"`},
+	}, got)
+
+	// the fix is suggested only once
+	require.Len(diagnostics[0].SuggestedFixes, 1)
+	edit := diagnostics[0].SuggestedFixes[0].TextEdits[0]
+	require.Equal("testdata/a.go:4:1", fset.Position(edit.Pos).String())
+	require.Equal("testdata/a.go:9:1", fset.Position(edit.End).String())
+	require.Equal("\t\"fmt\"\n\t\"os\"\n\t\"strings\"\n\n\t\"github.com/owner/repo\"\n", string(edit.NewText))
+	for _, d := range diagnostics[1:] {
+		require.Empty(d.SuggestedFixes)
+	}
+}
+
+func TestAnalyzerRedundantAliases(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(Analyzer.Flags.Set("remove-redundant-aliases", "true"))
+	defer func() {
+		require.Nil(Analyzer.Flags.Set("remove-redundant-aliases", "false"))
+	}()
+
+	_, diagnostics, got := analyze(t, "testdata/alias.go")
+	require.Equal([]diagnostic{
+		{Pos: "testdata/alias.go:4:2", Category: "redundant-alias", Message: `redundant alias fmt of "fmt"`},
+	}, got)
+	require.Equal("	\"fmt\"\n\tstr \"strings\"\n", string(diagnostics[0].SuggestedFixes[0].TextEdits[0].NewText))
+}
+
+func TestAnalyzerConflictingNames(t *testing.T) {
+	_, _, got := analyze(t, "testdata/names.go")
+	require.Equal(t, []diagnostic{
+		{Pos: "testdata/names.go:5:2", Category: "conflicting-name", Message: `"path" is imported as p, the name is used by "os" at line 4`},
+	}, got)
+}
+
+func TestAnalyzerErrors(t *testing.T) {
+	fset := token.NewFileSet()
+	missing, err := parser.ParseFile(fset, "testdata/missing.go", "package testdata\n", 0)
+	require.Nil(t, err)
+	valid, err := parser.ParseFile(fset, "testdata/names.go", nil, 0)
+	require.Nil(t, err)
+
+	var got []string
+	pass := &analysis.Pass{
+		Analyzer: Analyzer,
+		Fset:     fset,
+		Files:    []*ast.File{missing, valid},
+		Report: func(d analysis.Diagnostic) {
+			got = append(got, fset.Position(d.Pos).String()+": "+d.Message)
+		},
+	}
+	_, err = Analyzer.Run(pass)
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"testdata/missing.go:1:1: open testdata/missing.go: no such file or directory",
+		`testdata/names.go:5:2: "path" is imported as p, the name is used by "os" at line 4`,
+	}, got)
+}
@@ -0,0 +1,14 @@
+package a
+
+import (
+	"os"
+	"fmt"
+	"github.com/owner/repo"
+
+	"strings"
+)
+
+var _ = fmt.Println
+var _ = os.Exit
+var _ = repo.X
+var _ = strings.Split
@@ -0,0 +1,8 @@
+package testdata
+
+import (
+	fmt "fmt"
+	str "strings"
+)
+
+var _ = fmt.Sprint(str.ToUpper(""))
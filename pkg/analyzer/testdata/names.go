@@ -0,0 +1,8 @@
+package testdata
+
+import (
+	p "os"
+	p "path"
+	"unsafe"
+	_ "unsafe"
+)
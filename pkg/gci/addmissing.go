@@ -0,0 +1,152 @@
+package gci
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// addedImport is an import added by edits
+type addedImport struct {
+	// name is empty if the package is named as the last element of its path
+	name string
+	// path is quoted
+	path string
+}
+
+// missingImports returns edits adding imports of packages referenced in the
+// file, they are found in the standard library, the module and its
+// dependencies. Imports are only added to an existing import block, files
+// without it are skipped by formatFile, files that can't be parsed are not
+// changed
+func missingImports(filename string, src []byte) (*importEdits, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, nil
+	}
+
+	var (
+		refs          = packageRefs(f)
+		imported      = make(map[string]bool)
+		importedPaths = make(map[string]bool)
+		unnamed       []string
+	)
+	for _, s := range f.Imports {
+		importedPaths[unquote(s.Path.Value)] = true
+		if s.Name != nil {
+			imported[s.Name.Name] = true
+			continue
+		}
+		path := unquote(s.Path.Value)
+		imported[importPathBase(path)] = true
+		unnamed = append(unnamed, path)
+	}
+
+	missing := missingNames(refs, imported)
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	// the last element of the path may be not the name of the package
+	if len(unnamed) > 0 {
+		names, err := packageNames(filename)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range unnamed {
+			if names[path] != "" {
+				imported[names[path]] = true
+			}
+		}
+	}
+	// identifiers may be declared in other files of the package
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	for name := range packageDecls(filepath.Dir(abs), f.Name.Name, abs) {
+		imported[name] = true
+	}
+
+	missing = missingNames(refs, imported)
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	var (
+		e   importEdits
+		idx = moduleIndex(filepath.Dir(abs))
+	)
+	for _, name := range missing {
+		p, ok := idx.find(name, refs[name])
+		// the package may be imported with another name
+		if !ok || importedPaths[p.path] {
+			continue
+		}
+		added := addedImport{path: strconv.Quote(p.path)}
+		if importPathBase(p.path) != name {
+			added.name = name
+		}
+		e.added = append(e.added, added)
+	}
+	if e.added == nil {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// missingNames returns sorted names of referenced packages that are not
+// imported
+func missingNames(refs map[string]map[string]bool, imported map[string]bool) []string {
+	var ret []string
+	for name := range refs {
+		if !imported[name] {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// packageDecls returns names declared at the package level by other files of
+// the package in the directory, external tests are another package
+func packageDecls(dir, pkgName, exclude string) map[string]bool {
+	ret := make(map[string]bool)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, name := range files {
+		if name == exclude {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), name, nil, 0)
+		if err != nil || f.Name.Name != pkgName {
+			continue
+		}
+		for name := range f.Scope.Objects {
+			ret[name] = true
+		}
+	}
+	return ret
+}
+
+// packageRefs returns selectors of undeclared identifiers, they refer to
+// imported packages
+func packageRefs(f *ast.File) map[string]map[string]bool {
+	ret := make(map[string]map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+				if ret[x.Name] == nil {
+					ret[x.Name] = make(map[string]bool)
+				}
+				ret[x.Name][sel.Sel.Name] = true
+			}
+		}
+		return true
+	})
+	return ret
+}
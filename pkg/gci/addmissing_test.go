@@ -0,0 +1,119 @@
+package gci
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddMissing(t *testing.T) {
+	t.Parallel()
+
+	// packages are loaded once, so all files are written before adding imports
+	dir := newModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc Helper() {}\n",
+		"b.go":         "package main\n\nvar cfg struct{ Name string }\n",
+		"c.go": `package main
+
+import (
+	r "math/rand"
+)
+
+var _ = rand.Intn(r.Intn(1))
+`,
+		"a.go": `package main
+
+import (
+	"fmt"
+)
+
+func main() {
+	var b strings.Builder
+	fmt.Println(rand.Intn(1), cfg.Name, unknown.Name)
+	util.Helper()
+	lib.F()
+	_ = b
+}
+`,
+	})
+
+	set := &FlagSet{LocalFlag: "example.com/m", AddMissing: true, VerifyIdempotent: true}
+	_, res, err := Run(filepath.Join(dir, "a.go"), set)
+	require.Nil(t, err)
+	require.Equal(t, `package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"example.com/m/util"
+	lib "example.com/m/v2"
+)
+
+func main() {
+	var b strings.Builder
+	fmt.Println(rand.Intn(1), cfg.Name, unknown.Name)
+	util.Helper()
+	lib.F()
+	_ = b
+}
+`, string(res))
+
+	// imports with another name are not added again
+	_, res, err = Run(filepath.Join(dir, "c.go"), set)
+	require.Nil(t, err)
+	require.Nil(t, res)
+}
+
+func TestAddMissingSkipped(t *testing.T) {
+	t.Parallel()
+
+	dir := newModule(t, map[string]string{
+		"a.go": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(strings.ToUpper(\"a\"))\n}\n",
+		"b.go": "package main\n\nfunc main() {}\n",
+	})
+
+	set := &FlagSet{AddMissing: true}
+	r := formatFile(filepath.Join(dir, "a.go"), set, false)
+	require.Nil(t, r.Err)
+	require.Equal(t, StatusSkipped, r.Status)
+	require.Equal(t, "missing imports can't be added without an import block", r.Reason)
+
+	r = formatFile(filepath.Join(dir, "b.go"), set, false)
+	require.Equal(t, StatusSkipped, r.Status)
+	require.Equal(t, "no import", r.Reason)
+}
+
+func TestAddMissingExternalTests(t *testing.T) {
+	t.Parallel()
+
+	dir := newModule(t, map[string]string{
+		"a.go":      "package a\n\nvar strings struct{ Title string }\n",
+		"a_test.go": "package a_test\n\nimport (\n\t\"testing\"\n)\n\nfunc TestA(t *testing.T) {\n\t_ = strings.ToUpper(\"a\")\n}\n",
+	})
+
+	// names declared by the package under test are not visible
+	_, res, err := Run(filepath.Join(dir, "a_test.go"), &FlagSet{AddMissing: true})
+	require.Nil(t, err)
+	require.Equal(t, "package a_test\n\nimport (\n\t\"strings\"\n\t\"testing\"\n)\n\nfunc TestA(t *testing.T) {\n\t_ = strings.ToUpper(\"a\")\n}\n", string(res))
+}
+
+func TestAddMissingKeepsGoMod(t *testing.T) {
+	t.Parallel()
+
+	// go list adds the missing go directive if go.mod may be updated
+	goMod := "module example.com/m\n"
+	dir := newModule(t, map[string]string{
+		"go.mod": goMod,
+		"a.go":   "package main\n\nimport (\n\t\"fmt\"\n)\n\nfunc main() {\n\tfmt.Println(yaml.Marshal)\n}\n",
+	})
+
+	_, _, err := Run(filepath.Join(dir, "a.go"), &FlagSet{AddMissing: true})
+	require.Nil(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	require.Nil(t, err)
+	require.Equal(t, goMod, string(data))
+}
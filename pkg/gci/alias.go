@@ -0,0 +1,126 @@
+package gci
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// redundantAliases returns edits removing names of imports that are the
+// same as names declared by the packages. Names are loaded from package
+// clauses, they are guessed from paths like goimports does if packages are
+// not found. Names of major versions like v2 are kept. Files that can't be
+// parsed are not changed
+func redundantAliases(filename string, src []byte) (*importEdits, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, nil
+	}
+
+	var decl *ast.GenDecl
+	for _, d := range f.Decls {
+		if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.IMPORT && d.Lparen.IsValid() {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		return nil, nil
+	}
+
+	var (
+		e     importEdits
+		names map[string]string
+	)
+	for _, s := range decl.Specs {
+		s := s.(*ast.ImportSpec)
+		// names of major versions are kept, they are not clear without them
+		if s.Name == nil || s.Name.Name == "_" || s.Name.Name == "." || isMajorVersion(s.Name.Name) {
+			continue
+		}
+
+		if names == nil {
+			// packages may be not found in GOROOT, the module and its
+			// dependencies, the heuristic is used then
+			names, _ = packageNames(filename)
+			if names == nil {
+				names = make(map[string]string)
+			}
+		}
+		path := unquote(s.Path.Value)
+		name := names[path]
+		if name == "" {
+			name = assumedName(path)
+		}
+		if name != s.Name.Name {
+			continue
+		}
+
+		if e.unaliased == nil {
+			e.unaliased = make(map[string]bool)
+		}
+		e.unaliased[specKey(s.Name.Name, s.Path.Value)] = true
+	}
+	if e.unaliased == nil {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// aliasViolations returns violations of imports with redundant names
+func aliasViolations(src []byte, e *importEdits) []Violation {
+	if e == nil || e.unaliased == nil {
+		return nil
+	}
+	specs, err := parseImports(src)
+	if err != nil {
+		return nil
+	}
+
+	var ret []Violation
+	for _, spec := range specs {
+		if e.unaliased[specKey(spec.Name, spec.Path)] {
+			ret = append(ret, Violation{
+				Kind:      RedundantAlias,
+				Message:   "redundant alias " + spec.Name + " of " + spec.Path,
+				Path:      unquote(spec.Path),
+				Line:      spec.Line,
+				Column:    spec.Column,
+				EndColumn: spec.EndColumn,
+			})
+		}
+	}
+	return ret
+}
+
+// assumedName returns the name of the package guessed from its path: the
+// major version suffix and the go- prefix are removed, as well as everything
+// after the first character that isn't allowed in identifiers
+func assumedName(path string) string {
+	base := importPathBase(path)
+	if isMajorVersion(base) {
+		base = importPathBase(strings.TrimSuffix(path, "/"+base))
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// isMajorVersion reports whether s is a major version suffix like v2
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' || s[1] == '0' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
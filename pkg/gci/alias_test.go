@@ -0,0 +1,65 @@
+package gci
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedundantAliases(t *testing.T) {
+	t.Parallel()
+
+	dir := newModule(t, map[string]string{
+		"a.go": `package main
+
+import (
+	fmt "fmt" // fmt
+	str "strings"
+	lib "example.com/m/v2"
+	_ "embed"
+)
+
+func main() {
+	fmt.Println(str.ToUpper("a"))
+	lib.F()
+}
+`,
+	})
+	filename := filepath.Join(dir, "a.go")
+
+	set := &FlagSet{RemoveRedundantAliases: true, VerifyIdempotent: true}
+	violations, fix, err := Lint(filename, set)
+	require.Nil(t, err)
+	require.Equal(t, []Violation{
+		{Kind: RedundantAlias, Message: `redundant alias fmt of "fmt"`, Path: "fmt", Line: 4, Column: 2, EndColumn: 11},
+		{Kind: WrongSection, Message: `"example.com/m/v2" belongs in section "default" but is in "standard"`, Path: "example.com/m/v2", Line: 6, Column: 2, EndColumn: 24},
+		{Kind: MissingBlankLine, Message: `missing blank line between sections "standard" and "default"`, Path: "example.com/m/v2", Line: 6, Column: 2, EndColumn: 24},
+		{Kind: RedundantAlias, Message: `redundant alias lib of "example.com/m/v2"`, Path: "example.com/m/v2", Line: 6, Column: 2, EndColumn: 24},
+		{Kind: MissingBlankLine, Message: `missing blank line between sections "default" and "standard"`, Path: "embed", Line: 7, Column: 2, EndColumn: 11},
+		{Kind: WrongOrder, Message: `"embed" should come before "strings"`, Path: "embed", Line: 7, Column: 2, EndColumn: 11},
+	}, violations)
+	require.NotNil(t, fix)
+	require.Equal(t, "\t_ \"embed\"\n\t\"fmt\" // fmt\n\tstr \"strings\"\n\n\t\"example.com/m/v2\"\n", fix.Text)
+
+	// names are guessed from paths if packages can't be loaded
+	edits, err := redundantAliases(filepath.Join(dir, "missing", "a.go"), []byte(`package a
+
+import (
+	fmt "fmt"
+	yaml "gopkg.in/yaml.v3"
+	v2 "example.com/m/v2"
+	m "example.com/m/v3"
+	bar "example.com/go-bar"
+	foo "example.com/foo-go"
+)
+`))
+	require.Nil(t, err)
+	require.Equal(t, &importEdits{unaliased: map[string]bool{
+		`fmt "fmt"`:                true,
+		`yaml "gopkg.in/yaml.v3"`:  true,
+		`m "example.com/m/v3"`:     true,
+		`bar "example.com/go-bar"`: true,
+		`foo "example.com/foo-go"`: true,
+	}}, edits)
+}
@@ -0,0 +1,201 @@
+package gci
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// BackupDir is the default directory of backups
+	BackupDir = ".gci-backup"
+
+	manifestFile = "manifest.jsonl"
+)
+
+// Backup stores originals of rewritten files, so they can be restored by Undo.
+// Every run has its own directory named by its start time
+type Backup struct {
+	dir string
+
+	mu       sync.Mutex
+	manifest *os.File
+	n        int
+}
+
+// backupEntry is a line of the manifest
+type backupEntry struct {
+	// Path is an absolute path of the rewritten file
+	Path string `json:"path"`
+	// Backup is a name of the original file in the backup directory
+	Backup string `json:"backup"`
+	// Hash is a hash of the written content, the file is restored only if
+	// it is not modified after gci
+	Hash string `json:"hash"`
+}
+
+// NewBackup creates a directory for the backup of the current run inside of root
+func NewBackup(root string) (*Backup, error) {
+	dir := filepath.Join(root, time.Now().UTC().Format("20060102T150405.000000000Z"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Backup{dir: dir}, nil
+}
+
+// save stores the original file before it is rewritten with the result,
+// the manifest is appended immediately, so it is valid even if gci crashes
+func (b *Backup) save(filename string, original, result []byte) error {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.manifest == nil {
+		if b.manifest, err = os.OpenFile(filepath.Join(b.dir, manifestFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return err
+		}
+	}
+
+	b.n++
+	entry := backupEntry{
+		Path:   path,
+		Backup: strconv.Itoa(b.n) + ".orig",
+		Hash:   hash(result),
+	}
+	// originals may be private, so backups are readable only by the owner
+	if err := ioutil.WriteFile(filepath.Join(b.dir, entry.Backup), original, 0o600); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := b.manifest.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return b.manifest.Sync()
+}
+
+// Close closes the manifest, the backup directory is removed if no files
+// are rewritten
+func (b *Backup) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.manifest == nil {
+		if err := os.Remove(b.dir); err != nil {
+			return err
+		}
+		// remove the root as well if there are no other backups
+		_ = os.Remove(filepath.Dir(b.dir))
+		return nil
+	}
+	return b.manifest.Close()
+}
+
+// Undo restores files rewritten by the last run with the backup inside of root.
+// Files modified after the run are not restored and kept in the backup, it is
+// removed once all files are restored
+func Undo(root string, out io.Writer) error {
+	dir, err := lastBackup(root)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return err
+	}
+
+	var (
+		errs ErrorList
+		// remaining entries are kept in the manifest
+		remaining bytes.Buffer
+	)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		var entry backupEntry
+		if err := json.Unmarshal(s.Bytes(), &entry); err != nil {
+			return fmt.Errorf("invalid manifest %s: %v", dir, err)
+		}
+
+		if err := restore(dir, entry, out); err != nil {
+			errs = append(errs, err)
+			remaining.Write(s.Bytes())
+			remaining.WriteByte('\n')
+		}
+	}
+
+	if remaining.Len() == 0 {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		return nil
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, manifestFile), remaining.Bytes(), 0o644); err != nil {
+		errs = append(errs, err)
+	}
+	return errs.Err()
+}
+
+func restore(dir string, entry backupEntry, out io.Writer) error {
+	current, err := ioutil.ReadFile(entry.Path)
+	if err != nil {
+		return err
+	}
+	if hash(current) != entry.Hash {
+		return fmt.Errorf("%s is modified after gci, it is not restored", entry.Path)
+	}
+
+	original, err := ioutil.ReadFile(filepath.Join(dir, entry.Backup))
+	if err != nil {
+		return err
+	}
+	if err := writeFile(entry.Path, original, &FlagSet{FollowSymlinks: true}); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "restore file %s\n", entry.Path)
+	return nil
+}
+
+// lastBackup returns the directory of the last run
+func lastBackup(root string) (string, error) {
+	infos, err := ioutil.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	var dirs []string
+	for _, fi := range infos {
+		if fi.IsDir() {
+			dirs = append(dirs, fi.Name())
+		}
+	}
+	if len(dirs) == 0 {
+		return "", errors.New("there is nothing to undo")
+	}
+	sort.Strings(dirs)
+	return filepath.Join(root, dirs[len(dirs)-1]), nil
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
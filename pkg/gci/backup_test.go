@@ -0,0 +1,105 @@
+package gci
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupUndo(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gci")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	src, err := ioutil.ReadFile("testdata/1.in.go")
+	require.Nil(err)
+	want, err := ioutil.ReadFile("testdata/1.want.go")
+	require.Nil(err)
+
+	files := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go"), filepath.Join(dir, "c.go")}
+	for _, name := range files[:2] {
+		require.Nil(ioutil.WriteFile(name, src, 0o644))
+	}
+	require.Nil(ioutil.WriteFile(files[2], want, 0o644))
+
+	root := filepath.Join(dir, BackupDir)
+	backup, err := NewBackup(root)
+	require.Nil(err)
+
+	newBool := func(v bool) *bool { return &v }
+	flagSet := &FlagSet{
+		LocalFlag: "github.com/local/repo",
+		DoWrite:   newBool(true),
+		DoDiff:    newBool(false),
+		Backup:    backup,
+		NoIgnore:  true,
+	}
+	require.Nil(walkDir(dir, ioutil.Discard, flagSet))
+	require.Nil(backup.Close())
+
+	if runtime.GOOS != "windows" {
+		backups, err := filepath.Glob(filepath.Join(root, "*", "*.orig"))
+		require.Nil(err)
+		require.Len(backups, 2)
+		for _, name := range backups {
+			fi, err := os.Stat(name)
+			require.Nil(err)
+			require.Equal(os.FileMode(0o600), fi.Mode().Perm())
+		}
+	}
+
+	requireFile := func(name string, want []byte) {
+		data, err := ioutil.ReadFile(name)
+		require.Nil(err)
+		require.Equal(string(want), string(data))
+	}
+	for _, name := range files {
+		requireFile(name, want)
+	}
+
+	// the modified file is not restored
+	modified := append(want, "// modified\n"...)
+	require.Nil(ioutil.WriteFile(files[1], modified, 0o644))
+
+	buf := bytes.NewBuffer(nil)
+	require.NotNil(Undo(root, buf))
+	require.Equal("restore file "+files[0]+"\n", buf.String())
+	requireFile(files[0], src)
+	requireFile(files[1], modified)
+	requireFile(files[2], want)
+
+	// the rest of the backup can be restored later
+	require.Nil(ioutil.WriteFile(files[1], want, 0o644))
+
+	buf.Reset()
+	require.Nil(Undo(root, buf))
+	require.Equal("restore file "+files[1]+"\n", buf.String())
+	requireFile(files[1], src)
+
+	require.EqualError(Undo(root, buf), "there is nothing to undo")
+}
+
+func TestBackupWithoutChanges(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gci")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, BackupDir)
+	backup, err := NewBackup(root)
+	require.Nil(t, err)
+	require.Nil(t, backup.Close())
+
+	_, err = os.Stat(root)
+	require.True(t, os.IsNotExist(err))
+}
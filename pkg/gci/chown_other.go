@@ -0,0 +1,11 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package gci
+
+import "os"
+
+// chown is a no-op, there are no uid and gid on this platform
+func chown(string, os.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package gci
+
+import (
+	"os"
+	"syscall"
+)
+
+// chown sets the owner of the original file, it is ignored if the current
+// user has no permission for it
+func chown(name string, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	err := os.Lchown(name, int(st.Uid), int(st.Gid))
+	if os.IsPermission(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package gci
+
+// importEdits are changes of imports made in addition to formatting, a nil
+// value makes no changes
+type importEdits struct {
+	// removed are keys of imports to remove, see specKey
+	removed map[string]bool
+	// added are imports to add if they are not in the block
+	added []addedImport
+	// unaliased are keys of imports whose names are removed
+	unaliased map[string]bool
+}
+
+// importEdits returns changes of imports of the file enabled by the flags
+func (set *FlagSet) importEdits(filename string, src []byte) (*importEdits, error) {
+	src = normalized(src)
+
+	var e importEdits
+	if set.Prune {
+		pruned, err := unusedImports(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		if pruned != nil {
+			e.removed = pruned.removed
+		}
+	}
+	if set.AddMissing {
+		missing, err := missingImports(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		if missing != nil {
+			e.added = missing.added
+		}
+	}
+
+	if set.RemoveRedundantAliases {
+		aliases, err := redundantAliases(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		if aliases != nil {
+			e.unaliased = aliases.unaliased
+		}
+	}
+
+	if e.removed == nil && e.added == nil && e.unaliased == nil {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// specKey identifies an import by its name and quoted path
+func specKey(name, path string) string {
+	if name == "" {
+		return path
+	}
+	return name + blank + path
+}
+
+func (e *importEdits) isRemoved(name, path string) bool {
+	return e != nil && e.removed[specKey(name, path)]
+}
+
+// name returns the name of the import after edits
+func (e *importEdits) name(name, path string) string {
+	if e != nil && e.unaliased[specKey(name, path)] {
+		return ""
+	}
+	return name
+}
+
+// apply changes imports of the block
+func (p *pkg) apply(e *importEdits, localFlag string) {
+	if e == nil {
+		return
+	}
+
+	for _, added := range e.added {
+		section := getPkgType(added.path, localFlag)
+		key := specKey(added.name, added.path)
+		if contains(p.list[section], key) {
+			continue
+		}
+		p.list[section] = append(p.list[section], key)
+		if added.name != "" {
+			p.alias[key] = added.name
+		}
+	}
+
+	for section, list := range p.list {
+		var kept []string
+		for _, key := range list {
+			alias, path := p.alias[key], p.path(key)
+			switch {
+			case e.isRemoved(alias, path):
+				delete(p.alias, key)
+				delete(p.comments, key)
+			case alias != "" && e.name(alias, path) == "":
+				// the import without the name may already exist, then
+				// they are merged as exact duplicates
+				delete(p.alias, key)
+				if comments, ok := p.comments[key]; ok {
+					delete(p.comments, key)
+					p.comments[path] = append(p.comments[path], comments...)
+				}
+				if !contains(kept, path) {
+					kept = append(kept, path)
+				}
+			case !contains(kept, key):
+				kept = append(kept, key)
+			}
+		}
+		p.list[section] = kept
+	}
+}
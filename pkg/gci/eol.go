@@ -0,0 +1,55 @@
+package gci
+
+import "bytes"
+
+var (
+	bom  = []byte("\xef\xbb\xbf")
+	crlf = []byte("\r\n")
+	lf   = []byte(linebreak)
+)
+
+// textStyle holds conventions of a file that are removed before formatting
+// and restored in the result
+type textStyle struct {
+	// bom is set if the file starts with the UTF-8 byte order mark
+	bom bool
+	// crlf is set if every line of the file ends with "\r\n", files with
+	// mixed line endings are formatted as they are
+	crlf bool
+}
+
+func detectStyle(src []byte) textStyle {
+	s := textStyle{bom: bytes.HasPrefix(src, bom)}
+	if n := bytes.Count(src, lf); n > 0 && n == bytes.Count(src, crlf) {
+		s.crlf = true
+	}
+	return s
+}
+
+// normalize removes the byte order mark and replaces "\r\n" with "\n",
+// the number of lines is kept
+func (s textStyle) normalize(src []byte) []byte {
+	if s.bom {
+		src = src[len(bom):]
+	}
+	if s.crlf {
+		src = bytes.ReplaceAll(src, crlf, lf)
+	}
+	return src
+}
+
+// restore reverts normalize
+func (s textStyle) restore(src []byte) []byte {
+	if s.crlf {
+		src = bytes.ReplaceAll(src, lf, crlf)
+	}
+	if s.bom {
+		src = append(append([]byte{}, bom...), src...)
+	}
+	return src
+}
+
+// normalized returns src without the byte order mark and "\r\n" line endings
+func normalized(src []byte) []byte {
+	return detectStyle(src).normalize(src)
+}
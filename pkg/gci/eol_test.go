@@ -0,0 +1,88 @@
+package gci
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextStyle(t *testing.T) {
+	t.Parallel()
+
+	const (
+		src = `package main
+
+import (
+	"github.com/owner/repo" // repo
+	"fmt"
+)
+`
+		want = `package main
+
+import (
+	"fmt"
+
+	"github.com/owner/repo" // repo
+)
+`
+	)
+
+	tests := []struct {
+		desc  string
+		conv  func(string) string
+		style textStyle
+	}{
+		{
+			desc: "lf",
+			conv: func(s string) string { return s },
+		},
+		{
+			desc:  "crlf",
+			conv:  func(s string) string { return strings.ReplaceAll(s, "\n", "\r\n") },
+			style: textStyle{crlf: true},
+		},
+		{
+			desc:  "bom",
+			conv:  func(s string) string { return "\xef\xbb\xbf" + s },
+			style: textStyle{bom: true},
+		},
+		{
+			desc:  "bom and crlf",
+			conv:  func(s string) string { return "\xef\xbb\xbf" + strings.ReplaceAll(s, "\n", "\r\n") },
+			style: textStyle{bom: true, crlf: true},
+		},
+		{
+			desc: "mixed",
+			conv: func(s string) string { return strings.Replace(s, "\n", "\r\n", 1) },
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			in := []byte(tt.conv(src))
+			require.Equal(t, tt.style, detectStyle(in))
+			normalized := src
+			if tt.style == (textStyle{}) {
+				// there is nothing to normalize
+				normalized = string(in)
+			}
+			require.Equal(t, normalized, string(tt.style.normalize(in)))
+
+			got, ok, err := formatSource("a.go", in, &FlagSet{Gofmt: true, VerifyIdempotent: true})
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, tt.conv(want), string(got))
+
+			fix, ok := blockFix(&Result{Original: in, Formatted: got})
+			require.True(t, ok)
+			require.Equal(t, Fix{
+				StartLine: 4,
+				EndLine:   6,
+				Text:      string(textStyle{crlf: tt.style.crlf}.restore([]byte("\t\"fmt\"\n\n\t\"github.com/owner/repo\" // repo\n"))),
+			}, fix)
+		})
+	}
+}
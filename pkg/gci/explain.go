@@ -0,0 +1,75 @@
+package gci
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ExplainDir explains imports of Go files of the directory, the directory is
+// walked as by WalkDir
+func ExplainDir(root string, out io.Writer, set *FlagSet) error {
+	w, err := newWalker(root, set)
+	if err != nil {
+		return err
+	}
+	if err := filepath.Walk(root, w.visitFile); err != nil {
+		w.errs = append(w.errs, err)
+	}
+	for _, filename := range w.files {
+		if err := Explain(filename, out, set); err != nil {
+			w.errs = append(w.errs, err)
+		}
+	}
+	return w.errs.Err()
+}
+
+// Explain prints how every import of the file is assigned to its section
+func Explain(filename string, out io.Writer, set *FlagSet) error {
+	src, err := readFile(filename)
+	if err != nil {
+		return err
+	}
+	specs, err := parseImports(src)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s: standard library packages of %s\n", filename, standardPackagesSource)
+	for _, spec := range specs {
+		pkgName := strings.Trim(spec.Path, "\"`")
+
+		var matched []string
+		for i, r := range sectionRules {
+			// the last rule matches everything, it is listed only if it
+			// is the rule of the import
+			if i == len(sectionRules)-1 && len(matched) > 0 {
+				break
+			}
+			if r.match(pkgName, set.LocalFlag) {
+				matched = append(matched, ruleName(r, set.LocalFlag))
+			}
+		}
+
+		alias := spec.Name
+		if alias == "" {
+			alias = "-"
+		}
+		fmt.Fprintf(out, "%d: %s\n", spec.Line, spec.Path)
+		fmt.Fprintf(out, "\talias:   %s\n", alias)
+		fmt.Fprintf(out, "\tsection: %s\n", sectionNames[getPkgType(spec.Path, set.LocalFlag)])
+		fmt.Fprintf(out, "\trule:    %s\n", matched[0])
+		if len(matched) > 1 {
+			fmt.Fprintf(out, "\talso matched: %s\n", strings.Join(matched[1:], ", "))
+		}
+	}
+	return nil
+}
+
+func ruleName(r sectionRule, localFlag string) string {
+	if r.section == local {
+		return fmt.Sprintf("%s %q", r.name, localFlag)
+	}
+	return r.name
+}
@@ -0,0 +1,86 @@
+package gci
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.NewBuffer(nil)
+	err := Explain("testdata/1.want.go", buf, &FlagSet{LocalFlag: "github.com/local/repo/pkg1"})
+	require.Nil(t, err)
+
+	want := `testdata/1.want.go: standard library packages of go1.16beta1
+4: "embed"
+	alias:   _
+	section: standard
+	rule:    standard library
+6: "fmt"
+	alias:   -
+	section: standard
+	rule:    standard library
+8: "github.com/jackc/pgx/v4/stdlib"
+	alias:   _
+	section: default
+	rule:    default
+11: "github.com/local/repo/pkg1"
+	alias:   -
+	section: local
+	rule:    local prefix "github.com/local/repo/pkg1"
+13: "github.com/local/repo/pkg2"
+	alias:   -
+	section: default
+	rule:    default
+`
+	require.Equal(t, want, buf.String())
+}
+
+func TestExplainDir(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gci")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.go":        "package a\n\nimport (\n\t\"fmt\"\n)\n",
+		"b.txt":       "package a\n",
+		"vendor/b.go": "package b\n\nimport (\n\t\"os\"\n)\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.Nil(t, ioutil.WriteFile(path, []byte(data), 0o644))
+	}
+
+	buf := bytes.NewBuffer(nil)
+	require.Nil(t, ExplainDir(dir, buf, &FlagSet{SkipDirs: DefaultSkipDirs}))
+
+	want := filepath.Join(dir, "a.go") + `: standard library packages of go1.16beta1
+4: "fmt"
+	alias:   -
+	section: standard
+	rule:    standard library
+`
+	require.Equal(t, want, buf.String())
+}
+
+func TestExplainAlsoMatched(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.NewBuffer(nil)
+	require.Nil(t, Explain("testdata/1.want.go", buf, &FlagSet{LocalFlag: "embed"}))
+	require.Contains(t, buf.String(), `4: "embed"
+	alias:   _
+	section: local
+	rule:    local prefix "embed"
+	also matched: standard library
+6: "fmt"`)
+}
@@ -3,13 +3,19 @@ package gci
 import (
 	"bytes"
 	"fmt"
+	goformat "go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"unicode"
 )
 
 const (
@@ -19,7 +25,9 @@ const (
 	remote
 	local
 
-	commentFlag = "//"
+	commentFlag       = "//"
+	blockCommentStart = "/*"
+	blockCommentEnd   = "*/"
 )
 
 var (
@@ -29,17 +37,90 @@ import (
 	importEndFlag = []byte(`
 )
 `)
+
+	// see https://golang.org/s/generatedcode
+	generatedFlag = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
 )
 
 type FlagSet struct {
 	LocalFlag       string
 	DoWrite, DoDiff *bool
+	// Jobs is the number of files processed concurrently by WalkDir,
+	// GOMAXPROCS is used if it is not positive
+	Jobs int
+	// SkipDirs and SkipFiles are glob patterns of directories and files
+	// that are not processed by WalkDir. Patterns are matched against
+	// the base name and the slash-separated path relative to the walked root
+	SkipDirs, SkipFiles []string
+	// NoIgnore disables skipping of files matched by .gitignore and .gciignore
+	NoIgnore bool
+	// ChangedSince and Staged limit WalkDir to files that differ from
+	// the git revision or are staged
+	ChangedSince string
+	Staged       bool
+	// IncludeGenerated enables processing of generated files
+	IncludeGenerated bool
+	// Reporter prints results, files are printed or diffed depending on
+	// DoWrite and DoDiff if it is nil
+	Reporter Reporter
+	// Summary, if it is not nil, counts all reported files
+	Summary *Summary
+	// FollowSymlinks allows to rewrite targets of symlinks, PreserveMtime
+	// keeps the modification time of rewritten files
+	FollowSymlinks, PreserveMtime bool
+	// Backup, if it is not nil, stores originals of rewritten files
+	Backup *Backup
+	// VerifyIdempotent formats results again and fails files that are
+	// changed by the second pass
+	VerifyIdempotent bool
+	// Gofmt formats results with gofmt if the original file is gofmt-clean
+	Gofmt bool
+	// SectionHeaders are comments put before imports of sections, they are
+	// keyed by section names: "standard", "default" and "local"
+	SectionHeaders map[string]string
+	// Prune removes imports that are not referenced in files
+	Prune bool
+	// AddMissing adds imports of referenced packages found in the standard
+	// library, the module and its dependencies
+	AddMissing bool
+	// RemoveRedundantAliases removes names of imports that are the same as
+	// names of the packages
+	RemoveRedundantAliases bool
+}
+
+// sectionHeaders returns SectionHeaders keyed by sections, texts without the
+// comment mark are turned into line comments
+func (set *FlagSet) sectionHeaders() map[int]string {
+	if len(set.SectionHeaders) == 0 {
+		return nil
+	}
+
+	ret := make(map[int]string, len(set.SectionHeaders))
+	for section, name := range sectionNames {
+		header, ok := set.SectionHeaders[name]
+		if !ok || header == "" {
+			continue
+		}
+		if !strings.HasPrefix(header, commentFlag) && !strings.HasPrefix(header, blockCommentStart) {
+			header = commentFlag + blank + header
+		}
+		ret[section] = header
+	}
+	return ret
 }
 
 type pkg struct {
+	// imports are identified by their name and path, see specKey, the same
+	// package may be imported more than once with different names
 	list     map[int][]string
 	comments map[string][]importComment
 	alias    map[string]string
+	// headers and footers are comments separated from imports by a blank
+	// line, they are kept before and after imports of the section. Every
+	// comment is a list of lines, maps are nil if there are no such comments
+	headers, footers map[int][][]string
+	// sectionHeaders are comments put right before imports of the section
+	sectionHeaders map[int]string
 }
 
 type importComment struct {
@@ -54,25 +135,52 @@ func newPkg(data [][]byte, localFlag string) *pkg {
 		alias:    make(map[string]string),
 	}
 
-	formatData := make([]string, 0, len(data))
-	// remove all empty lines
-	for _, v := range data {
-		line := strings.TrimSpace(string(v))
-		if line != "" {
-			formatData = append(formatData, line)
-		}
-	}
+	formatData := importLines(data)
 
 	n := len(formatData)
-	var lastPkg string
-	for i := n - 1; i >= 0; i-- {
-		line := formatData[i]
+	var (
+		lastPkg string
+		// nextSection is the section of the closest import after the line
+		nextSection = -1
+		// floating is a comment that is not followed by an import
+		floating []string
+		// trailing are floating comments after the last import
+		trailing [][]string
+	)
+	for i := n - 1; i >= -1; i-- {
+		var line string
+		if i >= 0 {
+			line = formatData[i]
+		}
+
+		if line == "" {
+			// a blank line or the start of the block ends the floating
+			// comment, it goes to the section of the next import
+			if floating != nil {
+				if nextSection >= 0 {
+					p.addHeader(nextSection, floating)
+				} else {
+					trailing = append([][]string{floating}, trailing...)
+				}
+				floating = nil
+			}
+			lastPkg = ""
+			continue
+		}
+
+		// a block comment before the import on the same line is kept as
+		// its doc comment
+		var leadingComment string
+		if tokens := scanLine(line); len(tokens) > 1 && tokens[0].tok == token.COMMENT &&
+			strings.HasPrefix(tokens[0].lit, blockCommentStart) && tokens[1].tok != token.COMMENT {
+			leadingComment, line = tokens[0].lit, line[tokens[1].offset:]
+		}
 
-		commentIndex := strings.Index(line, commentFlag)
+		commentIndex := commentIndex(line)
 		if commentIndex == 0 {
 			// one line comment
 			if lastPkg == "" {
-				// comment in the last line is useless, ignore it
+				floating = append([]string{line}, floating...)
 				continue
 			}
 			p.comments[lastPkg] = append(p.comments[lastPkg], importComment{comment: line, sameLine: false})
@@ -81,33 +189,139 @@ func newPkg(data [][]byte, localFlag string) *pkg {
 
 		hasComment := commentIndex > 0
 		pkg, alias, comment := getPkgInfo(line, hasComment)
+		key := specKey(alias, pkg)
 		if alias != "" {
-			p.alias[pkg] = alias
+			p.alias[key] = alias
 		}
 		if comment != "" {
-			p.comments[pkg] = append(p.comments[pkg], importComment{comment: comment, sameLine: true})
+			p.comments[key] = append(p.comments[key], importComment{comment: comment, sameLine: true})
+		}
+		if leadingComment != "" {
+			p.comments[key] = append(p.comments[key], importComment{comment: leadingComment, sameLine: false})
 		}
 
-		lastPkg = pkg
+		lastPkg = key
 
 		pkgType := getPkgType(pkg, localFlag)
-		p.list[pkgType] = append(p.list[pkgType], pkg)
+		// exact duplicates are removed, their comments are kept
+		if !contains(p.list[pkgType], key) {
+			p.list[pkgType] = append(p.list[pkgType], key)
+		}
+
+		// comments between the import and the next blank line are after
+		// the last import of its group
+		if floating != nil {
+			trailing = append([][]string{floating}, trailing...)
+			floating = nil
+		}
+		p.addFooters(pkgType, trailing)
+		trailing = nil
+		nextSection = pkgType
 	}
 
+	// the import block has only comments
+	p.addFooters(standard, trailing)
+
 	return p
 }
 
+// path returns the quoted path of the import
+func (p *pkg) path(key string) string {
+	if alias := p.alias[key]; alias != "" {
+		return key[len(alias+blank):]
+	}
+	return key
+}
+
+func (p *pkg) addHeader(section int, comment []string) {
+	if p.headers == nil {
+		p.headers = make(map[int][][]string)
+	}
+	// comments are found from the end of the block
+	p.headers[section] = append([][]string{comment}, p.headers[section]...)
+}
+
+func (p *pkg) addFooters(section int, comments [][]string) {
+	if len(comments) == 0 {
+		return
+	}
+	if p.footers == nil {
+		p.footers = make(map[int][][]string)
+	}
+	p.footers[section] = append(comments, p.footers[section]...)
+}
+
+// setSectionHeaders puts the comments before imports of sections, the same
+// comments found in the import block are removed
+func (p *pkg) setSectionHeaders(headers map[int]string) {
+	if len(headers) == 0 {
+		return
+	}
+	p.sectionHeaders = headers
+
+	isHeader := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		isHeader[h] = true
+	}
+	for path, comments := range p.comments {
+		kept := comments[:0]
+		for _, c := range comments {
+			if c.sameLine || !isHeader[c.comment] {
+				kept = append(kept, c)
+			}
+		}
+		p.comments[path] = kept
+	}
+	for _, floating := range []map[int][][]string{p.headers, p.footers} {
+		for section, groups := range floating {
+			var kept [][]string
+			for _, group := range groups {
+				var lines []string
+				for _, line := range group {
+					if !isHeader[line] {
+						lines = append(lines, line)
+					}
+				}
+				if lines != nil {
+					kept = append(kept, lines)
+				}
+			}
+			floating[section] = kept
+		}
+	}
+}
+
 // fmt format import pkgs as expected
 func (p *pkg) fmt() []byte {
-	ret := make([]string, 0, 100)
+	sections := make([]string, 0, 3)
 
 	for pkgType := range []int{standard, remote, local} {
-		sort.Strings(p.list[pkgType])
+		// gofmt sorts imports by unquoted paths, so do raw string paths,
+		// and then by names
+		list := p.list[pkgType]
+		sort.Slice(list, func(i, j int) bool {
+			if a, b := unquote(p.path(list[i])), unquote(p.path(list[j])); a != b {
+				return a < b
+			}
+			return p.alias[list[i]] < p.alias[list[j]]
+		})
+
+		// parts of the section are separated by blank lines
+		var parts []string
+		for _, c := range p.headers[pkgType] {
+			parts = append(parts, fmtComment(c))
+		}
+
+		ret := make([]string, 0, 100)
+		if header := p.sectionHeaders[pkgType]; header != "" && len(p.list[pkgType]) > 0 {
+			ret = append(ret, indent+header+linebreak)
+		}
 		for _, s := range p.list[pkgType] {
 			var sameLineComment string
 			for i := len(p.comments[s]) - 1; i >= 0; i-- {
 				c := p.comments[s][i]
-				if c.sameLine {
+				// same line comments of removed duplicates are put above
+				if c.sameLine && sameLineComment == "" {
 					sameLineComment = c.comment
 					continue
 				}
@@ -120,7 +334,7 @@ func (p *pkg) fmt() []byte {
 			if p.alias[s] != "" {
 				line += p.alias[s] + blank
 			}
-			line += s
+			line += p.path(s)
 			if sameLineComment != "" {
 				line += blank + sameLineComment
 			}
@@ -128,54 +342,208 @@ func (p *pkg) fmt() []byte {
 
 			ret = append(ret, line)
 		}
+		if len(ret) > 0 {
+			parts = append(parts, strings.Join(ret, ""))
+		}
+
+		for _, c := range p.footers[pkgType] {
+			parts = append(parts, fmtComment(c))
+		}
+		if len(parts) > 0 {
+			sections = append(sections, strings.Join(parts, linebreak))
+		}
+	}
+
+	return []byte(strings.Join(sections, linebreak))
+}
 
-		if len(p.list[pkgType]) > 0 {
-			ret = append(ret, linebreak)
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
 		}
 	}
-	if ret[len(ret)-1] == linebreak {
+	return false
+}
+
+// fmtComment formats lines of a floating comment
+func fmtComment(lines []string) string {
+	var ret string
+	for _, l := range lines {
+		ret += indent + l + linebreak
+	}
+	return ret
+}
+
+// importLines returns lines of the import block without leading and trailing
+// spaces, blank lines are empty strings and consecutive ones are merged.
+// Lines of a block comment are joined in one line and its inner lines are
+// kept as is
+func importLines(data [][]byte) []string {
+	ret := make([]string, 0, len(data))
+
+	var block []string
+	for _, v := range data {
+		line := string(v)
+		if block == nil {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				if len(ret) > 0 && ret[len(ret)-1] != "" {
+					ret = append(ret, "")
+				}
+				continue
+			}
+			if hasOpenBlockComment(line) {
+				block = []string{line}
+				continue
+			}
+			ret = append(ret, line)
+			continue
+		}
+
+		block = append(block, line)
+		end := strings.Index(line, blockCommentEnd)
+		if end < 0 || hasOpenBlockComment(line[end+len(blockCommentEnd):]) {
+			continue
+		}
+		ret = append(ret, strings.TrimRightFunc(strings.Join(block, linebreak), unicode.IsSpace))
+		block = nil
+	}
+	if block != nil {
+		ret = append(ret, strings.Join(block, linebreak))
+	}
+	if len(ret) > 0 && ret[len(ret)-1] == "" {
 		ret = ret[:len(ret)-1]
 	}
+	return ret
+}
+
+// lineToken is a token of a line of the import block
+type lineToken struct {
+	offset int
+	tok    token.Token
+	lit    string
+}
+
+// scanLine splits the line into tokens with go/scanner, comments are kept and
+// automatically inserted semicolons are skipped
+func scanLine(line string) []lineToken {
+	var (
+		s   scanner.Scanner
+		ret []lineToken
+	)
+	file := token.NewFileSet().AddFile("", -1, len(line))
+	// errors such as unterminated comments are expected in single lines
+	s.Init(file, []byte(line), func(token.Position, string) {}, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return ret
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		ret = append(ret, lineToken{offset: file.Offset(pos), tok: tok, lit: lit})
+	}
+}
+
+// commentIndex returns the index of the first comment in the line, -1 if
+// there is no comment
+func commentIndex(line string) int {
+	for _, t := range scanLine(line) {
+		if t.tok == token.COMMENT {
+			return t.offset
+		}
+	}
+	return -1
+}
 
-	return []byte(strings.Join(ret, ""))
+// hasOpenBlockComment reports whether a block comment started in the line
+// continues on the next line
+func hasOpenBlockComment(line string) bool {
+	tokens := scanLine(line)
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	return last.tok == token.COMMENT && strings.HasPrefix(last.lit, blockCommentStart) &&
+		(len(last.lit) < len(blockCommentStart)+len(blockCommentEnd) || !strings.HasSuffix(last.lit, blockCommentEnd))
 }
 
 // getPkgInfo assume line is a import path, and return (path, alias, comment)
 func getPkgInfo(line string, hasComment bool) (path string, alias string, comment string) {
 	if hasComment {
-		s := strings.Split(line, commentFlag)
-
-		// Remove space after package name
-		pkgImport := strings.TrimSpace(s[0])
+		i := commentIndex(line)
 		// Don't remove space before comment text for `//nolint` and etc.
-		comment := commentFlag + s[1]
+		line, comment = line[:i], line[i:]
+	}
 
-		pkgArray := strings.Split(pkgImport, blank)
-		if len(pkgArray) > 1 {
-			// Import with alias
-			return pkgArray[1], pkgArray[0], comment
+	var tokens []lineToken
+	for _, t := range scanLine(line) {
+		if t.tok != token.COMMENT {
+			tokens = append(tokens, t)
 		}
-		return strings.TrimSpace(pkgArray[0]), "", comment
+	}
+	switch {
+	case len(tokens) == 1 && tokens[0].tok == token.STRING:
+		return tokens[0].lit, "", comment
+	case len(tokens) == 2 && tokens[1].tok == token.STRING && tokens[0].tok == token.IDENT:
+		return tokens[1].lit, tokens[0].lit, comment
+	case len(tokens) == 2 && tokens[1].tok == token.STRING && tokens[0].tok == token.PERIOD:
+		return tokens[1].lit, token.PERIOD.String(), comment
 	}
 
-	pkgArray := strings.Split(line, blank)
-	if len(pkgArray) > 1 {
-		return pkgArray[1], pkgArray[0], ""
+	// not a valid import spec, keep its fields
+	pkgArray := strings.Fields(line)
+	switch len(pkgArray) {
+	case 0:
+		return "", "", comment
+	case 1:
+		return pkgArray[0], "", comment
 	}
-	return pkgArray[0], "", ""
+	return pkgArray[1], pkgArray[0], comment
+}
+
+// sectionRule assigns matched packages to the section
+type sectionRule struct {
+	name    string
+	section int
+	match   func(pkgName, localFlag string) bool
+}
+
+// sectionRules are checked in order, the first matched rule wins
+var sectionRules = []sectionRule{
+	{
+		name:    "local prefix",
+		section: local,
+		match: func(pkgName, localFlag string) bool {
+			return localFlag != "" && strings.HasPrefix(pkgName, localFlag)
+		},
+	},
+	{
+		name:    "standard library",
+		section: standard,
+		match: func(pkgName, _ string) bool {
+			return isStandardPackage(pkgName)
+		},
+	},
+	{
+		name:    "default",
+		section: remote,
+		match: func(string, string) bool {
+			return true
+		},
+	},
 }
 
 func getPkgType(line, localFlag string) int {
 	pkgName := strings.Trim(line, "\"\\`")
 
-	if localFlag != "" && strings.HasPrefix(pkgName, localFlag) {
-		return local
-	}
-
-	if isStandardPackage(pkgName) {
-		return standard
+	for _, r := range sectionRules {
+		if r.match(pkgName, localFlag) {
+			return r.section
+		}
 	}
-
 	return remote
 }
 
@@ -254,124 +622,230 @@ func replaceTempFilename(diff []byte, filename string) ([]byte, error) {
 	return bytes.Join(bs, []byte{'\n'}), nil
 }
 
-func visitFile(set *FlagSet) filepath.WalkFunc {
-	return func(path string, f os.FileInfo, err error) error {
-		if err == nil && isGoFile(f) {
-			err = processFile(path, os.Stdout, set)
+// isGenerated reports whether src has the "Code generated ... DO NOT EDIT."
+// comment before the package clause
+func isGenerated(src []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false
+	}
+	for _, group := range f.Comments {
+		if group.Pos() > f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if generatedFlag.MatchString(c.Text) {
+				return true
+			}
 		}
-		return err
 	}
+	return false
 }
 
-func WalkDir(path string, set *FlagSet) error {
-	return filepath.Walk(path, visitFile(set))
+func ProcessFile(filename string, out io.Writer, set *FlagSet) error {
+	r := processFile(filename, set)
+	if err := set.report(out, r); err != nil {
+		return err
+	}
+	return r.Err
 }
 
-func isGoFile(f os.FileInfo) bool {
-	// ignore non-Go files
-	name := f.Name()
-	return !f.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go")
-}
+// processFile formats the file and rewrites it if needed
+func processFile(filename string, set *FlagSet) *Result {
+	r := formatFile(filename, set, set.needViolations())
+	if r.Status != StatusChanged {
+		return r
+	}
 
-func ProcessFile(filename string, out io.Writer, set *FlagSet) error {
-	return processFile(filename, out, set)
+	if *set.DoWrite {
+		if set.Backup != nil {
+			if err := set.Backup.save(filename, r.Original, r.Formatted); err != nil {
+				return r.fail(fmt.Errorf("failed to backup: %v", err))
+			}
+		}
+		if err := writeFile(filename, r.Formatted, set); err != nil {
+			return r.fail(err)
+		}
+	}
+	if set.needDiff() {
+		data, err := diff(r.Original, r.Formatted, filename)
+		if err != nil {
+			return r.fail(fmt.Errorf("failed to diff: %v", err))
+		}
+		r.Diff = data
+	}
+	return r
 }
 
-func processFile(filename string, out io.Writer, set *FlagSet) error {
-	var err error
+// formatFile reads and formats the file without writing it, violations are
+// linted only if withViolations is set since it parses the file again
+func formatFile(filename string, set *FlagSet, withViolations bool) *Result {
+	r := &Result{Filename: filename}
 
-	f, err := os.Open(filename)
+	src, err := readFile(filename)
 	if err != nil {
-		return err
+		return r.fail(err)
+	}
+	r.Original = src
+
+	if !set.IncludeGenerated && isGenerated(src) {
+		return r.skip("it is generated")
 	}
-	defer f.Close()
 
-	src, err := ioutil.ReadAll(f)
+	edits, err := set.importEdits(filename, src)
 	if err != nil {
-		return err
+		return r.fail(err)
+	}
+	res, ok, err := formatEdited(filename, src, set, edits)
+	if err != nil {
+		return r.fail(err)
+	}
+	if !ok {
+		if edits != nil && edits.added != nil {
+			return r.skip("missing imports can't be added without an import block")
+		}
+		return r.skip("no import")
+	}
+	r.Formatted = res
+
+	r.Status = StatusUnchanged
+	if withViolations {
+		r.Violations = lint(src, set.LocalFlag)
+		if v := aliasViolations(src, edits); v != nil {
+			r.Violations = append(r.Violations, v...)
+			sort.SliceStable(r.Violations, func(i, j int) bool {
+				return r.Violations[i].Line < r.Violations[j].Line
+			})
+		}
+	}
+	if !bytes.Equal(src, res) {
+		r.Status = StatusChanged
+		if set.needMoves() {
+			r.Moves = moves(src, res, set.LocalFlag)
+		}
+	}
+	return r
+}
+
+func readFile(filename string) ([]byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
 
-	ori := make([]byte, len(src))
-	copy(ori, src)
-	start := bytes.Index(src, importStartFlag)
+// importBlock returns offsets of the content of the import block: from
+// the line after "import (" to the closing parenthesis
+func importBlock(src []byte) (start, end int, ok bool) {
+	start = bytes.Index(src, importStartFlag)
 	// in case no importStartFlag or importStartFlag exist in the commentFlag
 	if start < 0 {
-		fmt.Printf("skip file %s since no import\n", filename)
-		return nil
+		return 0, 0, false
 	}
-	end := bytes.Index(src[start:], importEndFlag) + start
-
-	ret := bytes.Split(src[start+len(importStartFlag):end], []byte(linebreak))
+	end = bytes.Index(src[start:], importEndFlag) + start
+	return start + len(importStartFlag), end + 1, true
+}
 
-	p := newPkg(ret, set.LocalFlag)
+// formatSource formats src and verifies the result, ok is false if there is
+// no import block
+func formatSource(filename string, src []byte, set *FlagSet) (res []byte, ok bool, err error) {
+	edits, err := set.importEdits(filename, src)
+	if err != nil {
+		return nil, false, err
+	}
+	return formatEdited(filename, src, set, edits)
+}
 
-	res := append(src[:start+len(importStartFlag)], append(p.fmt(), src[end+1:]...)...)
+// formatEdited is formatSource with edits of imports
+func formatEdited(filename string, src []byte, set *FlagSet, edits *importEdits) (res []byte, ok bool, err error) {
+	res, ok = formatOnce(src, set, edits)
+	if !ok || bytes.Equal(src, res) {
+		return res, ok, nil
+	}
 
-	if !bytes.Equal(ori, res) {
-		if *set.DoWrite {
-			// On Windows, we need to re-set the permissions from the file. See golang/go#38225.
-			var perms os.FileMode
-			if fi, err := os.Stat(filename); err == nil {
-				perms = fi.Mode() & os.ModePerm
-			}
-			err = ioutil.WriteFile(filename, res, perms)
-			if err != nil {
-				return err
-			}
-		}
-		if *set.DoDiff {
-			data, err := diff(ori, res, filename)
-			if err != nil {
-				return fmt.Errorf("failed to diff: %v", err)
-			}
-			fmt.Printf("diff -u %s %s\n", filepath.ToSlash(filename+".orig"), filepath.ToSlash(filename))
-			if _, err := out.Write(data); err != nil {
-				return fmt.Errorf("failed to write: %v", err)
-			}
-		}
+	if err := verify(src, res, set, edits); err != nil {
+		return nil, false, fmt.Errorf("%s: refusing to change the file: %v", filename, err)
 	}
-	if !*set.DoWrite && !*set.DoDiff {
-		if _, err = out.Write(res); err != nil {
-			return fmt.Errorf("failed to write: %v", err)
+	if set.VerifyIdempotent {
+		if err := verifyIdempotent(filename, src, res, set, edits); err != nil {
+			return nil, false, err
 		}
 	}
+	return res, true, nil
+}
 
-	return err
+// formatOnce is a single formatting pass without any verification
+func formatOnce(src []byte, set *FlagSet, edits *importEdits) ([]byte, bool) {
+	style := detectStyle(src)
+	src = style.normalize(src)
+
+	res, ok := format(src, set, edits)
+	if !ok {
+		return nil, false
+	}
+	if set.Gofmt && !bytes.Equal(src, res) {
+		res = gofmt(src, res)
+	}
+	return style.restore(res), true
+}
+
+// gofmt formats res with gofmt if its original src is gofmt-clean, files
+// that are not are left as is to avoid unrelated changes
+func gofmt(src, res []byte) []byte {
+	if clean, err := goformat.Source(src); err != nil || !bytes.Equal(src, clean) {
+		return res
+	}
+	if formatted, err := goformat.Source(res); err == nil {
+		return formatted
+	}
+	return res
+}
+
+// format formats the import block of src, ok is false if there is no import block
+func format(src []byte, set *FlagSet, edits *importEdits) (res []byte, ok bool) {
+	start, end, ok := importBlock(src)
+	if !ok {
+		return nil, false
+	}
+
+	ret := bytes.Split(src[start:end-1], []byte(linebreak))
+
+	p := newPkg(ret, set.LocalFlag)
+	p.apply(edits, set.LocalFlag)
+	p.setSectionHeaders(set.sectionHeaders())
+
+	res = make([]byte, 0, len(src))
+	res = append(res, src[:start]...)
+	res = append(res, p.fmt()...)
+	res = append(res, src[end:]...)
+	return res, true
 }
 
 // Run return source and result in []byte if succeed
 func Run(filename string, set *FlagSet) ([]byte, []byte, error) {
-	var err error
-
-	f, err := os.Open(filename)
+	src, err := readFile(filename)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer f.Close()
 
-	src, err := ioutil.ReadAll(f)
+	if !set.IncludeGenerated && isGenerated(src) {
+		return nil, nil, nil
+	}
+
+	res, ok, err := formatSource(filename, src, set)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	ori := make([]byte, len(src))
-	copy(ori, src)
-	start := bytes.Index(src, importStartFlag)
-	// in case no importStartFlag or importStartFlag exist in the commentFlag
-	if start < 0 {
+	if !ok {
 		return nil, nil, nil
 	}
-	end := bytes.Index(src[start:], importEndFlag) + start
-
-	ret := bytes.Split(src[start+len(importStartFlag):end], []byte(linebreak))
-
-	p := newPkg(ret, set.LocalFlag)
-
-	res := append(src[:start+len(importStartFlag)], append(p.fmt(), src[end+1:]...)...)
 
-	if bytes.Equal(ori, res) {
-		return ori, nil, nil
+	if bytes.Equal(src, res) {
+		return src, nil, nil
 	}
 
-	return ori, res, nil
+	return src, res, nil
 }
@@ -96,18 +96,18 @@ func TestNewPkg(t *testing.T) {
 `,
 			want: &pkg{
 				list: map[int][]string{
-					standard: {`"net/http/pprof"`, `"database/sql"`, `"log"`, `"fmt"`},
+					standard: {`_ "net/http/pprof"`, `_ "database/sql"`, `"log"`, `"fmt"`},
 					remote:   {`"github.com/owner/repo"`},
 				},
 				comments: map[string][]importComment{
-					`"fmt"`:            {{comment: "// same line comment", sameLine: true}},
-					`"log"`:            {{comment: "//nolint", sameLine: true}},
-					`"database/sql"`:   {{comment: "// import sql", sameLine: true}},
-					`"net/http/pprof"`: {{comment: "//nolint:golint", sameLine: true}},
+					`"fmt"`:              {{comment: "// same line comment", sameLine: true}},
+					`"log"`:              {{comment: "//nolint", sameLine: true}},
+					`_ "database/sql"`:   {{comment: "// import sql", sameLine: true}},
+					`_ "net/http/pprof"`: {{comment: "//nolint:golint", sameLine: true}},
 				},
 				alias: map[string]string{
-					`"database/sql"`:   "_",
-					`"net/http/pprof"`: "_",
+					`_ "database/sql"`:   "_",
+					`_ "net/http/pprof"`: "_",
 				},
 			},
 		},
@@ -123,15 +123,15 @@ func TestNewPkg(t *testing.T) {
 `,
 			want: &pkg{
 				list: map[int][]string{
-					standard: {`"log"`, `"database/sql"`},
+					standard: {`"log"`, `_ "database/sql"`},
 					remote:   {`"github.com/owner/repo"`},
 				},
 				comments: map[string][]importComment{
-					`"log"`:          {{comment: "//nolint", sameLine: false}},
-					`"database/sql"`: {{comment: "// import sql", sameLine: false}},
+					`"log"`:            {{comment: "//nolint", sameLine: false}},
+					`_ "database/sql"`: {{comment: "// import sql", sameLine: false}},
 				},
 				alias: map[string]string{
-					`"database/sql"`: "_",
+					`_ "database/sql"`: "_",
 				},
 			},
 		},
@@ -150,20 +150,56 @@ func TestNewPkg(t *testing.T) {
 `,
 			want: &pkg{
 				list: map[int][]string{
-					standard: {`"log"`, `"database/sql"`},
+					standard: {`"log"`, `_ "database/sql"`},
 				},
 				comments: map[string][]importComment{
 					`"log"`: {
 						{comment: "//nolint", sameLine: false},
 						{comment: "// Import log", sameLine: false},
 					},
-					`"database/sql"`: {
+					`_ "database/sql"`: {
 						{comment: "// sql", sameLine: false},
 						{comment: "// import", sameLine: false},
 					},
 				},
 				alias: map[string]string{
-					`"database/sql"`: "_",
+					`_ "database/sql"`: "_",
+				},
+				footers: map[int][][]string{
+					standard: {{"// First dangling comment"}, {"// Second dangling comment"}},
+				},
+			},
+		},
+		{
+			desc: "floating comments",
+			imports: `
+	// Header of the block
+
+	"fmt"
+	// End of standard
+
+	// Third party
+	// packages
+
+	"github.com/owner/repo"
+	// doc of os
+	"os"
+`,
+			want: &pkg{
+				list: map[int][]string{
+					standard: {`"os"`, `"fmt"`},
+					remote:   {`"github.com/owner/repo"`},
+				},
+				comments: map[string][]importComment{
+					`"os"`: {{comment: "// doc of os", sameLine: false}},
+				},
+				alias: map[string]string{},
+				headers: map[int][][]string{
+					standard: {{"// Header of the block"}},
+					remote:   {{"// Third party", "// packages"}},
+				},
+				footers: map[int][][]string{
+					standard: {{"// End of standard"}},
 				},
 			},
 		},
@@ -188,6 +224,63 @@ func TestNewPkg(t *testing.T) {
 				alias: map[string]string{},
 			},
 		},
+		{
+			desc: "tokens",
+			imports: `
+	"net/http" // see https://example.com
+	l	"log"
+	f    "fmt" /* see "os" */ // and "io"
+	. ` + "`os`" + `
+	"/*path*/"
+`,
+			want: &pkg{
+				list: map[int][]string{
+					standard: {". `os`", `f "fmt"`, `l "log"`, `"net/http"`},
+					remote:   {`"/*path*/"`},
+				},
+				comments: map[string][]importComment{
+					`"net/http"`: {{comment: "// see https://example.com", sameLine: true}},
+					`f "fmt"`:    {{comment: `/* see "os" */ // and "io"`, sameLine: true}},
+				},
+				alias: map[string]string{
+					`l "log"`: "l",
+					`f "fmt"`: "f",
+					". `os`":  ".",
+				},
+			},
+		},
+		{
+			desc: "block comments",
+			imports: `
+	/* import sql */
+	"database/sql" /* same line */
+	/*
+	 * Import log
+	 */
+	"log" // see /* not a block */
+	/* leading */ "fmt"
+	"os" /* multi
+	line */
+`,
+			want: &pkg{
+				list: map[int][]string{
+					standard: {`"os"`, `"fmt"`, `"log"`, `"database/sql"`},
+				},
+				comments: map[string][]importComment{
+					`"os"`:  {{comment: "/* multi\n\tline */", sameLine: true}},
+					`"fmt"`: {{comment: "/* leading */", sameLine: false}},
+					`"log"`: {
+						{comment: "// see /* not a block */", sameLine: true},
+						{comment: "/*\n\t * Import log\n\t */", sameLine: false},
+					},
+					`"database/sql"`: {
+						{comment: "/* same line */", sameLine: true},
+						{comment: "/* import sql */", sameLine: false},
+					},
+				},
+				alias: map[string]string{},
+			},
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -229,18 +322,18 @@ func TestFmtPkg(t *testing.T) {
 		{
 			pkg: &pkg{
 				list: map[int][]string{
-					standard: {`"net/http/pprof"`, `"database/sql"`, `"log"`, `"fmt"`},
+					standard: {`_ "net/http/pprof"`, `_ "database/sql"`, `"log"`, `"fmt"`},
 					remote:   {`"github.com/owner/repo"`},
 				},
 				comments: map[string][]importComment{
-					`"fmt"`:            {{comment: "// same line comment", sameLine: true}},
-					`"log"`:            {{comment: "//nolint", sameLine: true}},
-					`"database/sql"`:   {{comment: "// import sql", sameLine: true}},
-					`"net/http/pprof"`: {{comment: "//nolint:golint", sameLine: true}},
+					`"fmt"`:              {{comment: "// same line comment", sameLine: true}},
+					`"log"`:              {{comment: "//nolint", sameLine: true}},
+					`_ "database/sql"`:   {{comment: "// import sql", sameLine: true}},
+					`_ "net/http/pprof"`: {{comment: "//nolint:golint", sameLine: true}},
 				},
 				alias: map[string]string{
-					`"database/sql"`:   "_",
-					`"net/http/pprof"`: "_",
+					`_ "database/sql"`:   "_",
+					`_ "net/http/pprof"`: "_",
 				},
 			},
 			//
@@ -256,20 +349,20 @@ func TestFmtPkg(t *testing.T) {
 		{
 			pkg: &pkg{
 				list: map[int][]string{
-					standard: {`"log"`, `"database/sql"`},
+					standard: {`"log"`, `_ "database/sql"`},
 				},
 				comments: map[string][]importComment{
 					`"log"`: {
 						{comment: "//nolint", sameLine: false},
 						{comment: "// Import log", sameLine: false},
 					},
-					`"database/sql"`: {
+					`_ "database/sql"`: {
 						{comment: "// sql", sameLine: false},
 						{comment: "// import", sameLine: false},
 					},
 				},
 				alias: map[string]string{
-					`"database/sql"`: "_",
+					`_ "database/sql"`: "_",
 				},
 			},
 			//
@@ -369,6 +462,391 @@ func TestProcessFile(t *testing.T) {
 			require.Nil(err)
 
 			require.Equal(string(want), buf.String())
+
+			in, err := ioutil.ReadFile(inFilepath)
+			require.Nil(err)
+			requireIdempotent(t, flagSet, in)
+		})
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		src  string
+		want bool
+	}{
+		{
+			desc: "generated",
+			src:  "// Code generated by stringer. DO NOT EDIT.\n\npackage a\n",
+			want: true,
+		},
+		{
+			desc: "after build tags",
+			src:  "// +build linux\n\n// Code generated based on go1.16. DO NOT EDIT.\n\npackage a\n",
+			want: true,
+		},
+		{
+			desc: "crlf",
+			src:  "// Code generated by stringer. DO NOT EDIT.\r\n\r\npackage a\r\n",
+			want: true,
+		},
+		{
+			desc: "after package clause",
+			src:  "package a\n\n// Code generated by stringer. DO NOT EDIT.\n",
+			want: false,
+		},
+		{
+			desc: "without dot",
+			src:  "// Code generated by stringer. DO NOT EDIT\n\npackage a\n",
+			want: false,
+		},
+		{
+			desc: "regular comment",
+			src:  "// Package a is not generated.\npackage a\n",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			require.Equal(t, tt.want, isGenerated([]byte(tt.src)))
+		})
+	}
+}
+
+func TestGofmt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc  string
+		src   string
+		gofmt bool
+		want  string
+	}{
+		{
+			desc: "gofmt-clean",
+			src: `package a
+
+import (
+	"fmt"                         // fmt
+	"github.com/owner/repository" // repo
+	"os"                          // os
+)
+`,
+			gofmt: true,
+			want: `package a
+
+import (
+	"fmt" // fmt
+	"os"  // os
+
+	"github.com/owner/repository" // repo
+)
+`,
+		},
+		{
+			desc: "disabled",
+			src: `package a
+
+import (
+	"fmt"                         // fmt
+	"github.com/owner/repository" // repo
+	"os"                          // os
+)
+`,
+			want: `package a
+
+import (
+	"fmt" // fmt
+	"os" // os
+
+	"github.com/owner/repository" // repo
+)
+`,
+		},
+		{
+			desc: "not gofmt-clean",
+			src: `package a
+
+import (
+	"fmt"                         // fmt
+	"github.com/owner/repository" // repo
+	"os"                          // os
+)
+
+func  main() {}
+`,
+			gofmt: true,
+			want: `package a
+
+import (
+	"fmt" // fmt
+	"os" // os
+
+	"github.com/owner/repository" // repo
+)
+
+func  main() {}
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := formatOnce([]byte(tt.src), &FlagSet{Gofmt: tt.gofmt}, nil)
+			require.True(t, ok)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestBlockComments(t *testing.T) {
+	t.Parallel()
+
+	src := `package main
+
+import (
+	"github.com/owner/repo" /* repo */
+	/*
+	 * Import log
+	 */
+	"log"
+	/* leading */ "fmt"
+)
+`
+	want := `package main
+
+import (
+	/* leading */
+	"fmt"
+	/*
+	 * Import log
+	 */
+	"log"
+
+	"github.com/owner/repo" /* repo */
+)
+`
+
+	set := &FlagSet{VerifyIdempotent: true}
+	got, ok, err := formatSource("a.go", []byte(src), set)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, want, string(got))
+}
+
+func TestSpecTokens(t *testing.T) {
+	t.Parallel()
+
+	src := "package main\n\nimport (\n" +
+		"\t\"github.com/owner/repo\" // see https://example.com/a//b\n" +
+		"\tl\t\"log\"\n" +
+		"\t. `os`\n" +
+		")\n"
+	want := "package main\n\nimport (\n" +
+		"\tl \"log\"\n" +
+		"\t. `os`\n" +
+		"\n" +
+		"\t\"github.com/owner/repo\" // see https://example.com/a//b\n" +
+		")\n"
+
+	set := &FlagSet{VerifyIdempotent: true}
+	got, ok, err := formatSource("a.go", []byte(src), set)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, want, string(got))
+}
+
+func TestRawStringPaths(t *testing.T) {
+	t.Parallel()
+
+	// imports are sorted by unquoted paths as gofmt does
+	src := "package main\n\nimport (\n" +
+		"\t\"strings\"\n" +
+		"\t`fmt`\n" +
+		")\n"
+	want := "package main\n\nimport (\n" +
+		"\t`fmt`\n" +
+		"\t\"strings\"\n" +
+		")\n"
+
+	set := &FlagSet{Gofmt: true, VerifyIdempotent: true}
+	got, ok, err := formatSource("a.go", []byte(src), set)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, want, string(got))
+
+	require.Len(t, lint([]byte(src), ""), 1)
+	require.Empty(t, lint(got, ""))
+}
+
+func TestFloatingComments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		headers map[string]string
+		src     string
+		want    string
+	}{
+		{
+			desc: "floating",
+			src: `package main
+
+import (
+	// Header of the block
+
+	"os"
+	"github.com/owner/repo"
+	"fmt"
+	// End of standard
+
+	// Third party
+
+	"github.com/owner/another"
+	// Dangling comment
+)
+`,
+			want: `package main
+
+import (
+	// Header of the block
+
+	"fmt"
+	"os"
+
+	// End of standard
+
+	// Third party
+
+	"github.com/owner/another"
+	"github.com/owner/repo"
+
+	// Dangling comment
+)
+`,
+		},
+		{
+			desc: "section headers",
+			headers: map[string]string{
+				"standard": "Standard library",
+				"default":  "// Third party",
+			},
+			src: `package main
+
+import (
+	// Standard library
+	"os"
+	// Third party
+	"github.com/owner/repo"
+	// doc of fmt
+	"fmt"
+)
+`,
+			want: `package main
+
+import (
+	// Standard library
+	// doc of fmt
+	"fmt"
+	"os"
+
+	// Third party
+	"github.com/owner/repo"
+)
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			set := &FlagSet{SectionHeaders: tt.headers, VerifyIdempotent: true}
+			got, ok, err := formatSource("a.go", []byte(tt.src), set)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, tt.want, string(got))
+			require.Empty(t, lint(got, ""))
+		})
+	}
+}
+
+func TestDuplicateImports(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		src  string
+		//
+		want string
+	}{
+		{
+			desc: "exact duplicates",
+			src: `package main
+
+import (
+	"os" // first
+	"github.com/owner/repo"
+	// doc
+	"os" // second
+	_ "embed"
+	_ "embed"
+)
+`,
+			want: `package main
+
+import (
+	_ "embed"
+	// doc
+	// second
+	"os" // first
+
+	"github.com/owner/repo"
+)
+`,
+		},
+		{
+			desc: "different names",
+			src: `package main
+
+import (
+	urlpkg "net/url"
+	"github.com/owner/repo"
+	_ "unsafe" // for go:linkname
+	"unsafe"
+	r "github.com/owner/repo"
+	"net/url"
+)
+`,
+			want: `package main
+
+import (
+	"net/url"
+	urlpkg "net/url"
+	"unsafe"
+	_ "unsafe" // for go:linkname
+
+	"github.com/owner/repo"
+	r "github.com/owner/repo"
+)
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok, err := formatSource("a.go", []byte(tt.src), &FlagSet{VerifyIdempotent: true})
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, tt.want, string(got))
 		})
 	}
 }
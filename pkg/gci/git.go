@@ -0,0 +1,102 @@
+package gci
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// changeSet holds Go files that differ from a git revision
+type changeSet struct {
+	// absolute paths of files and all their parent directories
+	files, dirs map[string]struct{}
+}
+
+// gitChangedFiles returns Go files inside of dir that differ from the revision
+// (including untracked ones) or are staged, it calls the local git binary
+func gitChangedFiles(dir, revision string, staged bool) (*changeSet, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// git prints paths relative to the top-level directory
+	prefix, err := git(absDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	switch {
+	case staged:
+		args := []string{"diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z"}
+		if revision != "" {
+			args = append(args, revision)
+		}
+		out, err := git(absDir, append(args, "--")...)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, splitNUL(out)...)
+	default:
+		out, err := git(absDir, "diff", "--name-only", "--diff-filter=ACMR", "-z", revision, "--")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, splitNUL(out)...)
+
+		out, err = git(absDir, "ls-files", "--others", "--exclude-standard", "--full-name", "-z")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, splitNUL(out)...)
+	}
+
+	set := &changeSet{
+		files: make(map[string]struct{}),
+		dirs:  make(map[string]struct{}),
+	}
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".go") || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		path := filepath.Join(absDir, filepath.FromSlash(name[len(prefix):]))
+		set.files[path] = struct{}{}
+		for p := filepath.Dir(path); len(p) >= len(absDir); p = filepath.Dir(p) {
+			set.dirs[p] = struct{}{}
+		}
+	}
+	return set, nil
+}
+
+func (s *changeSet) hasFile(path string) bool {
+	_, ok := s.files[path]
+	return ok
+}
+
+func (s *changeSet) hasDir(path string) bool {
+	_, ok := s.dirs[path]
+	return ok
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	stderr := bytes.NewBuffer(nil)
+	cmd.Stderr = stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run git %s: %v: %s", strings.Join(args, blank), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return strings.TrimSuffix(string(out), linebreak), nil
+}
+
+func splitNUL(s string) []string {
+	s = strings.TrimSuffix(s, "\x00")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\x00")
+}
@@ -0,0 +1,109 @@
+package gci
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkDirChanged(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "gci")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	run := func(args ...string) {
+		args = append([]string{"-c", "user.name=gci", "-c", "user.email=gci@example.com"}, args...)
+		_, err := git(dir, args...)
+		require.Nil(t, err)
+	}
+	write := func(name, comment string) {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.Nil(t, ioutil.WriteFile(path, []byte("package a\n// "+comment+"\n"), 0o644))
+	}
+
+	run("init", "-q")
+	for _, name := range []string{"a.go", "b.go", "pkg/a.go", "pkg/b.go", "other/a.go"} {
+		write(name, "old")
+	}
+	run("add", "-A")
+	run("commit", "-q", "-m", "init")
+	run("tag", "base")
+
+	write("b.go", "new")
+	write("pkg/a.go", "new")
+	write("pkg/c.go", "new")
+	write("pkg/d.txt", "new")
+	run("add", "pkg/a.go")
+
+	tests := []struct {
+		desc         string
+		root         string
+		changedSince string
+		staged       bool
+		//
+		want []string
+	}{
+		{
+			desc:         "changed since revision",
+			root:         dir,
+			changedSince: "base",
+			want:         []string{"b.go", "pkg/a.go", "pkg/c.go"},
+		},
+		{
+			desc:         "subdirectory",
+			root:         filepath.Join(dir, "pkg"),
+			changedSince: "HEAD",
+			want:         []string{"pkg/a.go", "pkg/c.go"},
+		},
+		{
+			desc:   "staged",
+			root:   dir,
+			staged: true,
+			want:   []string{"pkg/a.go"},
+		},
+		{
+			desc:         "nothing changed",
+			root:         filepath.Join(dir, "other"),
+			changedSince: "HEAD",
+			want:         nil,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			newBool := func(v bool) *bool { return &v }
+			set := &FlagSet{
+				DoWrite:      newBool(false),
+				DoDiff:       newBool(false),
+				ChangedSince: tt.changedSince,
+				Staged:       tt.staged,
+			}
+
+			// skipped files are noted on stderr
+			buf := bytes.NewBuffer(nil)
+			set.Reporter = &textReporter{set: set, stderr: buf}
+			require.Nil(t, walkDir(tt.root, ioutil.Discard, set))
+
+			var want string
+			for _, name := range tt.want {
+				want += "skip file " + filepath.Join(dir, name) + " since no import\n"
+			}
+			require.Equal(t, want, buf.String())
+		})
+	}
+
+	_, err = gitChangedFiles(dir, "unknown-revision", false)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,70 @@
+package gci
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// githubReporter prints GitHub Actions workflow commands, so changed files
+// are annotated in pull requests
+type githubReporter struct{}
+
+func (githubReporter) Report(out io.Writer, r *Result) error {
+	var line, endLine int
+	message := "imports are not formatted by gci"
+
+	switch {
+	case r.Status == StatusError:
+		_, err := fmt.Fprintf(out, "::error file=%s::%s\n", escapeGithubProperty(filepath.ToSlash(r.Filename)), escapeGithubData(r.Err.Error()))
+		return err
+	case r.Status != StatusChanged:
+		return nil
+	case len(r.Violations) > 0:
+		// point at the first mis-ordered import spec
+		line = r.Violations[0].Line
+		endLine = line
+	case len(r.Moves) > 0:
+		line = r.Moves[0].OldLine
+		endLine = line
+	default:
+		src := normalized(r.Original)
+		if start, end, ok := importBlock(src); ok {
+			line = bytes.Count(src[:start], []byte(linebreak)) + 1
+			endLine = bytes.Count(src[:end], []byte(linebreak))
+		}
+	}
+
+	var details []string
+	for _, v := range r.Violations {
+		if v.Line == line {
+			details = append(details, v.Message)
+		}
+	}
+	if len(details) > 0 {
+		message += ": " + strings.Join(details, "; ")
+	}
+
+	_, err := fmt.Fprintf(out, "::error file=%s,line=%d,endLine=%d,title=gci::%s\n",
+		escapeGithubProperty(filepath.ToSlash(r.Filename)), line, endLine, escapeGithubData(message))
+	return err
+}
+
+func (githubReporter) Finish(io.Writer) error {
+	return nil
+}
+
+func (githubReporter) Needs() (diff, violations, moves bool) {
+	return false, true, true
+}
+
+// see https://github.com/actions/toolkit/blob/main/packages/core/src/command.ts
+func escapeGithubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGithubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
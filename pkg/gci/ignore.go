@@ -0,0 +1,242 @@
+package gci
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFiles are read in every walked directory
+var ignoreFiles = []string{".gitignore", ".gciignore"}
+
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreList holds patterns of all ignore files of one directory,
+// patterns are matched against paths relative to this directory
+type ignoreList struct {
+	dir      string
+	patterns []ignorePattern
+}
+
+// readIgnoreList reads ignore files of the directory, it returns nil if
+// there are no ignore files
+func readIgnoreList(dir string) (*ignoreList, error) {
+	l := &ignoreList{dir: dir}
+	for _, name := range ignoreFiles {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		l.parse(data)
+	}
+	if len(l.patterns) == 0 {
+		return nil, nil
+	}
+	return l, nil
+}
+
+// parse parses patterns in the .gitignore format, see https://git-scm.com/docs/gitignore
+func (l *ignoreList) parse(data []byte) {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// trailing spaces are ignored unless they are quoted with backslash
+		for strings.HasSuffix(line, blank) && !strings.HasSuffix(line, `\ `) {
+			line = line[:len(line)-1]
+		}
+
+		var p ignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// a pattern with a slash at the beginning or in the middle is relative
+		// to the directory of the ignore file, otherwise it matches at any level
+		prefix := "^(.*/)?"
+		if strings.Contains(line, "/") {
+			prefix = "^"
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		re, err := regexp.Compile(prefix + globToRegexp(line) + "$")
+		if err != nil {
+			// git ignores invalid patterns as well
+			continue
+		}
+		p.re = re
+		l.patterns = append(l.patterns, p)
+	}
+}
+
+// match matches the slash-separated path relative to the list directory,
+// the last matched pattern wins
+func (l *ignoreList) match(rel string, isDir bool) (matched, ignored bool) {
+	for _, p := range l.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			matched, ignored = true, !p.negate
+		}
+	}
+	return matched, ignored
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			// "**/" matches zero or more directories
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**") && i > 0 && glob[i-1] == '/' && i+2 == len(glob):
+			// trailing "/**" matches everything inside
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// ignorer tracks ignore files of directories visited by filepath.Walk,
+// a nil ignorer doesn't ignore anything
+type ignorer struct {
+	root, absRoot string
+	// lists of the current directory and its parents, from the topmost one
+	lists []*ignoreList
+}
+
+// newIgnorer loads ignore files of parent directories of root if root is
+// inside of a git repository
+func newIgnorer(root string) (*ignorer, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	ig := &ignorer{root: root, absRoot: absRoot}
+
+	// ignore files of root are read by the walk itself, only parents up to
+	// the closest repository root are loaded here
+	dir := absRoot
+	var parents []string
+	for !isRepoRoot(dir) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// there is no repository, files above root are unrelated
+			return ig, nil
+		}
+		dir = parent
+		parents = append(parents, dir)
+	}
+
+	for i := len(parents) - 1; i >= 0; i-- {
+		if err := ig.pushAbs(parents[i]); err != nil {
+			return nil, err
+		}
+	}
+	return ig, nil
+}
+
+func isRepoRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// abs converts a path passed by filepath.Walk to the absolute one
+func (ig *ignorer) abs(path string) string {
+	rel, err := filepath.Rel(ig.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(ig.absRoot, rel)
+}
+
+// push reads ignore files of the walked directory
+func (ig *ignorer) push(dir string) error {
+	if ig == nil {
+		return nil
+	}
+	return ig.pushAbs(ig.abs(dir))
+}
+
+func (ig *ignorer) pushAbs(dir string) error {
+	l, err := readIgnoreList(dir)
+	if err != nil || l == nil {
+		return err
+	}
+	ig.lists = append(ig.lists, l)
+	return nil
+}
+
+// isIgnored reports whether the walked path is ignored. Paths must be passed
+// in the filepath.Walk order to drop lists of already walked directories
+func (ig *ignorer) isIgnored(path string, isDir bool) bool {
+	if ig == nil {
+		return false
+	}
+	path = ig.abs(path)
+
+	for n := len(ig.lists); n > 0; n-- {
+		if strings.HasPrefix(path, ig.lists[n-1].dir+string(filepath.Separator)) {
+			break
+		}
+		ig.lists = ig.lists[:n-1]
+	}
+
+	var ignored bool
+	for _, l := range ig.lists {
+		rel, err := filepath.Rel(l.dir, path)
+		if err != nil {
+			continue
+		}
+		if ok, ign := l.match(filepath.ToSlash(rel), isDir); ok {
+			ignored = ign
+		}
+	}
+	return ignored
+}
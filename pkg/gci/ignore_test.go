@@ -0,0 +1,144 @@
+package gci
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreListMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		//
+		matched, ignored bool
+	}{
+		{pattern: "build", path: "build", isDir: true, matched: true, ignored: true},
+		{pattern: "build", path: "a/b/build", matched: true, ignored: true},
+		{pattern: "build/", path: "a/build", matched: false},
+		{pattern: "build/", path: "a/build", isDir: true, matched: true, ignored: true},
+		{pattern: "/build", path: "a/build", isDir: true, matched: false},
+		{pattern: "a/build", path: "a/build", isDir: true, matched: true, ignored: true},
+		{pattern: "a/build", path: "b/a/build", isDir: true, matched: false},
+		{pattern: "*.pb.go", path: "api/v1/api.pb.go", matched: true, ignored: true},
+		{pattern: "api/*.go", path: "api/v1/a.go", matched: false},
+		{pattern: "**/gen", path: "a/b/gen", isDir: true, matched: true, ignored: true},
+		{pattern: "**/gen", path: "gen", isDir: true, matched: true, ignored: true},
+		{pattern: "a/**/gen", path: "a/gen", isDir: true, matched: true, ignored: true},
+		{pattern: "a/**/gen", path: "a/b/c/gen", isDir: true, matched: true, ignored: true},
+		{pattern: "a/**", path: "a/b/c.go", matched: true, ignored: true},
+		{pattern: "file?.go", path: "file1.go", matched: true, ignored: true},
+		{pattern: "file[0-9].go", path: "filea.go", matched: false},
+		{pattern: "file[!0-9].go", path: "filea.go", matched: true, ignored: true},
+		{pattern: `\#file.go`, path: "#file.go", matched: true, ignored: true},
+		{pattern: "# comment", path: "# comment", matched: false},
+		{pattern: "*.go\n!main.go", path: "main.go", matched: true, ignored: false},
+		{pattern: "*.go\n!main.go", path: "a.go", matched: true, ignored: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.pattern+":"+tt.path, func(t *testing.T) {
+			l := &ignoreList{}
+			l.parse([]byte(tt.pattern))
+
+			matched, ignored := l.match(tt.path, tt.isDir)
+			require.Equal(t, tt.matched, matched)
+			require.Equal(t, tt.ignored, ignored)
+		})
+	}
+}
+
+func TestWalkDirIgnore(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gci")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		".git/HEAD":              "",
+		".gitignore":             "/build/\n*_gen.go\n",
+		"a.go":                   "package a\n",
+		"a_gen.go":               "package a\n",
+		"build/a.go":             "package a\n",
+		"pkg/.gitignore":         "!b_gen.go\nnode_modules\n",
+		"pkg/.gciignore":         "c.go\n",
+		"pkg/a_gen.go":           "package a\n",
+		"pkg/b_gen.go":           "package a\n",
+		"pkg/c.go":               "package a\n",
+		"pkg/node_modules/a.go":  "package a\n",
+		"pkg/sub/build/a.go":     "package a\n",
+		"pkg/sub/c.go":           "package a\n",
+		"other/pkg/b_gen.go":     "package a\n",
+		"other/pkg/sub/build.go": "package a\n",
+		"nested/.git/HEAD":       "",
+		"nested/a_gen.go":        "package a\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.Nil(t, ioutil.WriteFile(path, []byte(data), 0o644))
+	}
+
+	tests := []struct {
+		desc     string
+		root     string
+		noIgnore bool
+		//
+		want []string
+	}{
+		{
+			desc: "root",
+			root: dir,
+			want: []string{"a.go", "other/pkg/sub/build.go", "pkg/b_gen.go", "pkg/sub/build/a.go"},
+		},
+		{
+			desc: "ignore files of parents",
+			root: filepath.Join(dir, "pkg"),
+			want: []string{"pkg/b_gen.go", "pkg/sub/build/a.go"},
+		},
+		{
+			desc: "nested repository",
+			root: filepath.Join(dir, "nested"),
+			want: []string{"nested/a_gen.go"},
+		},
+		{
+			desc:     "disabled",
+			root:     filepath.Join(dir, "pkg"),
+			noIgnore: true,
+			want: []string{
+				"pkg/a_gen.go", "pkg/b_gen.go", "pkg/c.go", "pkg/node_modules/a.go",
+				"pkg/sub/build/a.go", "pkg/sub/c.go",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			newBool := func(v bool) *bool { return &v }
+			set := &FlagSet{
+				DoWrite:  newBool(false),
+				DoDiff:   newBool(false),
+				NoIgnore: tt.noIgnore,
+			}
+
+			// skipped files are noted on stderr
+			buf := bytes.NewBuffer(nil)
+			set.Reporter = &textReporter{set: set, stderr: buf}
+			require.Nil(t, walkDir(tt.root, ioutil.Discard, set))
+
+			var want string
+			for _, name := range tt.want {
+				want += "skip file " + filepath.Join(dir, name) + " since no import\n"
+			}
+			require.Equal(t, want, buf.String())
+		})
+	}
+}
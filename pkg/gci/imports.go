@@ -0,0 +1,169 @@
+package gci
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+var sectionNames = map[int]string{
+	standard: "standard",
+	remote:   "default",
+	local:    "local",
+}
+
+// IsSectionName reports whether name is the name of a section
+func IsSectionName(name string) bool {
+	for _, n := range sectionNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// importSpec is an import of the import block with its position
+type importSpec struct {
+	// Path is quoted as in pkg.list
+	Name, Path string
+	// StartLine includes the doc comment, EndLine includes the same line comment
+	StartLine, Line, EndLine int
+	// Column and EndColumn are columns of the spec itself on Line
+	Column, EndColumn int
+	// Group is the index of the group of imports separated by blank lines
+	Group int
+}
+
+// parseImports returns imports of the first parenthesized import declaration,
+// it is the block processed by gci
+func parseImports(src []byte) ([]importSpec, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var decl *ast.GenDecl
+	for _, d := range f.Decls {
+		if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.IMPORT && d.Lparen.IsValid() {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		return nil, nil
+	}
+
+	specs := make([]importSpec, 0, len(decl.Specs))
+	for _, s := range decl.Specs {
+		s := s.(*ast.ImportSpec)
+
+		pos, end := fset.Position(s.Pos()), fset.Position(s.End())
+		spec := importSpec{
+			Path:      s.Path.Value,
+			StartLine: pos.Line,
+			Line:      pos.Line,
+			EndLine:   end.Line,
+			Column:    pos.Column,
+			EndColumn: end.Column,
+		}
+		if s.Name != nil {
+			spec.Name = s.Name.Name
+		}
+		if s.Doc != nil {
+			spec.StartLine = fset.Position(s.Doc.Pos()).Line
+		}
+		if s.Comment != nil {
+			spec.EndLine = fset.Position(s.Comment.End()).Line
+		}
+		if n := len(specs); n > 0 {
+			prev := specs[n-1]
+			spec.Group = prev.Group
+			if spec.StartLine > prev.EndLine+1 {
+				spec.Group++
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// groupSections returns the section of every group: the section of most
+// imports of the group, or of the first one if there is a tie
+func groupSections(specs []importSpec, localFlag string) []int {
+	var sections []int
+	for i := 0; i < len(specs); {
+		group := specs[i].Group
+
+		counts := make(map[int]int)
+		best := getPkgType(specs[i].Path, localFlag)
+		for ; i < len(specs) && specs[i].Group == group; i++ {
+			pkgType := getPkgType(specs[i].Path, localFlag)
+			counts[pkgType]++
+			if counts[pkgType] > counts[best] {
+				best = pkgType
+			}
+		}
+		for len(sections) <= group {
+			sections = append(sections, best)
+		}
+	}
+	return sections
+}
+
+// Move describes an import moved by formatting
+type Move struct {
+	Path       string `json:"path"`
+	Name       string `json:"name,omitempty"`
+	OldSection string `json:"oldSection"`
+	NewSection string `json:"newSection"`
+	OldLine    int    `json:"oldLine"`
+	NewLine    int    `json:"newLine"`
+}
+
+// moves returns imports that changed their order or section
+func moves(src, res []byte, localFlag string) []Move {
+	oldSpecs, err := parseImports(src)
+	if err != nil {
+		return nil
+	}
+	newSpecs, err := parseImports(res)
+	if err != nil {
+		return nil
+	}
+
+	// the same import may be repeated
+	newIndexes := make(map[string][]int)
+	for i, s := range newSpecs {
+		key := s.Name + blank + s.Path
+		newIndexes[key] = append(newIndexes[key], i)
+	}
+
+	oldSections := groupSections(oldSpecs, localFlag)
+
+	var ret []Move
+	for i, old := range oldSpecs {
+		key := old.Name + blank + old.Path
+		if len(newIndexes[key]) == 0 {
+			continue
+		}
+		j := newIndexes[key][0]
+		newIndexes[key] = newIndexes[key][1:]
+
+		oldSection := sectionNames[oldSections[old.Group]]
+		newSection := sectionNames[getPkgType(old.Path, localFlag)]
+		if i == j && oldSection == newSection {
+			continue
+		}
+
+		ret = append(ret, Move{
+			Path:       unquote(old.Path),
+			Name:       old.Name,
+			OldSection: oldSection,
+			NewSection: newSection,
+			OldLine:    old.Line,
+			NewLine:    newSpecs[j].Line,
+		})
+	}
+	return ret
+}
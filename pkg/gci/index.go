@@ -0,0 +1,276 @@
+package gci
+
+import (
+	"bufio"
+	"bytes"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// indexedPackage is a package that can be imported by missing imports
+type indexedPackage struct {
+	path, name, dir string
+	// rank orders candidates: standard packages, packages of the module and
+	// its dependencies
+	rank int
+}
+
+// packageIndex finds packages by their names in the standard library,
+// the module and its dependencies without network access
+type packageIndex struct {
+	root, modulePath string
+
+	once     sync.Once
+	packages map[string][]indexedPackage
+
+	mu      sync.Mutex
+	exports map[string]map[string]bool
+}
+
+// indexes are shared by files of the same module
+var indexes sync.Map
+
+// moduleIndex returns the index of the module containing dir, or of the
+// standard library if there is no module
+func moduleIndex(dir string) *packageIndex {
+	root, modulePath := findModule(dir)
+	idx, _ := indexes.LoadOrStore(root, &packageIndex{root: root, modulePath: modulePath})
+	return idx.(*packageIndex)
+}
+
+var moduleFlag = regexp.MustCompile(`(?m)^module\s+"?([^"\s]+)"?\s*$`)
+
+// findModule returns the root directory and the path of the module
+func findModule(dir string) (root, modulePath string) {
+	for d := dir; ; d = filepath.Dir(d) {
+		if data, err := ioutil.ReadFile(filepath.Join(d, "go.mod")); err == nil {
+			if m := moduleFlag.FindSubmatch(data); m != nil {
+				return d, string(m[1])
+			}
+			return d, ""
+		}
+		if filepath.Dir(d) == d {
+			return "", ""
+		}
+	}
+}
+
+// find returns the best package with the name exporting all selectors
+func (idx *packageIndex) find(name string, selectors map[string]bool) (indexedPackage, bool) {
+	idx.once.Do(idx.load)
+
+	for _, c := range idx.packages[name] {
+		exports := idx.exportsOf(c.dir, c.name)
+		found := true
+		for sel := range selectors {
+			if !exports[sel] {
+				found = false
+				break
+			}
+		}
+		if found {
+			return c, true
+		}
+	}
+	return indexedPackage{}, false
+}
+
+func (idx *packageIndex) add(p indexedPackage) {
+	idx.packages[p.name] = append(idx.packages[p.name], p)
+}
+
+func (idx *packageIndex) load() {
+	idx.packages = make(map[string][]indexedPackage)
+
+	// names of standard packages are the last elements of their paths
+	goroot := filepath.Join(build.Default.GOROOT, "src")
+	for p := range standardPackages {
+		idx.add(indexedPackage{path: p, name: path.Base(p), dir: filepath.Join(goroot, filepath.FromSlash(p))})
+	}
+
+	if idx.root != "" {
+		if idx.modulePath != "" {
+			idx.walkModule(idx.modulePath, idx.root, 1)
+		}
+
+		if vendor := filepath.Join(idx.root, "vendor"); isFile(filepath.Join(vendor, "modules.txt")) {
+			idx.walkModule("", vendor, 2)
+		} else {
+			for _, m := range listModules(idx.root) {
+				idx.walkModule(m.path, m.dir, 2)
+			}
+		}
+	}
+
+	for _, candidates := range idx.packages {
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].rank != candidates[j].rank {
+				return candidates[i].rank < candidates[j].rank
+			}
+			if len(candidates[i].path) != len(candidates[j].path) {
+				return len(candidates[i].path) < len(candidates[j].path)
+			}
+			return candidates[i].path < candidates[j].path
+		})
+	}
+}
+
+// walkModule adds packages found in the directory of the module
+func (idx *packageIndex) walkModule(modulePath, root string, rank int) {
+	_ = filepath.Walk(root, func(dir string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		if dir != root {
+			base := fi.Name()
+			if base == "vendor" || base == "testdata" || strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") {
+				return filepath.SkipDir
+			}
+			// nested modules are not part of the module
+			if isFile(filepath.Join(dir, "go.mod")) {
+				return filepath.SkipDir
+			}
+		}
+
+		name := packageName(dir)
+		if name == "" || name == "main" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return nil
+		}
+		p := path.Join(modulePath, filepath.ToSlash(rel))
+		if rel == "." {
+			p = modulePath
+		}
+		// internal packages of dependencies can't be imported
+		if rank > 1 && (strings.Contains(p, "/internal/") || strings.HasSuffix(p, "/internal")) {
+			return nil
+		}
+		idx.add(indexedPackage{path: p, name: name, dir: dir, rank: rank})
+		return nil
+	})
+}
+
+// packageName returns the package name of non-test files of the directory
+func packageName(dir string) string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return ""
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), f, nil, parser.PackageClauseOnly)
+		if err == nil && file.Name.Name != "documentation" {
+			return file.Name.Name
+		}
+	}
+	return ""
+}
+
+// exportsOf returns exported names declared by non-test files of the package
+func (idx *packageIndex) exportsOf(dir, name string) map[string]bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if exports, ok := idx.exports[dir]; ok {
+		return exports
+	}
+	if idx.exports == nil {
+		idx.exports = make(map[string]map[string]bool)
+	}
+
+	exports := make(map[string]bool)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), f, nil, 0)
+		if err != nil || file.Name.Name != name {
+			continue
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil && decl.Name.IsExported() {
+					exports[decl.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if spec.Name.IsExported() {
+							exports[spec.Name.Name] = true
+						}
+					case *ast.ValueSpec:
+						for _, n := range spec.Names {
+							if n.IsExported() {
+								exports[n.Name] = true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	idx.exports[dir] = exports
+	return exports
+}
+
+type module struct {
+	path, dir string
+}
+
+// listModules returns dependencies of the module found in the module cache
+func listModules(root string) []module {
+	cmd := exec.Command("go", "list", "-m", "-f", "{{if not .Main}}{{.Path}}\t{{.Dir}}{{end}}", "all")
+	cmd.Dir = root
+	cmd.Env = goEnv(root)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var ret []module
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		fields := strings.Split(s.Text(), "\t")
+		// modules that are not downloaded have no directory
+		if len(fields) == 2 && fields[1] != "" {
+			ret = append(ret, module{path: fields[0], dir: fields[1]})
+		}
+	}
+	return ret
+}
+
+// goEnv returns the environment of go commands run in the directory, they
+// don't access the network and never update go.mod and go.sum. The vendor
+// directory of the module is used if it exists
+func goEnv(dir string) []string {
+	mod := "-mod=readonly"
+	if root, _ := findModule(dir); root != "" && isFile(filepath.Join(root, "vendor", "modules.txt")) {
+		mod = "-mod=vendor"
+	}
+	return append(os.Environ(), "GOPROXY=off", "GOFLAGS="+mod)
+}
+
+func isFile(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && !fi.IsDir()
+}
@@ -0,0 +1,39 @@
+package gci
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newModule creates the example.com/m module with files, its v2 package
+// declares package lib. The test is skipped if go is not found
+func newModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not found")
+	}
+
+	dir, err := ioutil.TempDir("", "gci")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	all := map[string]string{
+		"go.mod":   "module example.com/m\n\ngo 1.14\n",
+		"v2/v2.go": "package lib\n\nfunc F() {}\n",
+	}
+	for name, data := range files {
+		all[name] = data
+	}
+	for name, data := range all {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.Nil(t, ioutil.WriteFile(path, []byte(data), 0o600))
+	}
+	return dir
+}
@@ -0,0 +1,206 @@
+package gci
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// dirPackages are packages of a directory with their dependencies
+type dirPackages struct {
+	once  sync.Once
+	pkgs  []*packages.Package
+	names map[string]string
+	err   error
+}
+
+// dirPackagesCache holds packages by directories, all files of the directory
+// are loaded by a single go list run
+var dirPackagesCache sync.Map
+
+// loadDir loads packages of the directory including tests and all their
+// dependencies from GOROOT, the module cache and the vendor directory without
+// network access, see goEnv
+func loadDir(dir string) (*dirPackages, error) {
+	v, _ := dirPackagesCache.LoadOrStore(dir, &dirPackages{})
+	d := v.(*dirPackages)
+	d.once.Do(func() {
+		cfg := &packages.Config{
+			Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps,
+			Dir:   dir,
+			Env:   goEnv(dir),
+			Tests: true,
+		}
+		if d.pkgs, d.err = packages.Load(cfg, "."); d.err != nil {
+			d.err = fmt.Errorf("failed to load packages of %s: %v", dir, d.err)
+			return
+		}
+
+		d.names = make(map[string]string)
+		for _, p := range d.pkgs {
+			for path, imported := range p.Imports {
+				if imported.Name != "" {
+					d.names[path] = imported.Name
+				}
+			}
+		}
+	})
+	return d, d.err
+}
+
+// packageNames returns names of packages imported by packages of the file
+// directory. The map must not be modified
+func packageNames(filename string) (map[string]string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	d, err := loadDir(filepath.Dir(abs))
+	if err != nil {
+		return nil, err
+	}
+	return d.names, nil
+}
+
+// checkedPackage is a package type-checked once
+type checkedPackage struct {
+	once  sync.Once
+	types *types.Package
+	// uses are imports of files by their keys, see specKey, imports of
+	// packages that can't be loaded are missing
+	uses map[string]map[string]bool
+}
+
+var (
+	// checkedDeps are dependencies by package IDs, they are shared by all
+	// packages and checked without function bodies
+	checkedDeps sync.Map
+	// checkedFiles are packages whose imports are pruned by package IDs
+	checkedFiles sync.Map
+)
+
+// importUses returns whether imports of the file are used, it is nil if
+// the file is not a part of any package of its directory
+func importUses(filename string) (map[string]bool, error) {
+	d, err := loadDir(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range d.pkgs {
+		for _, name := range p.GoFiles {
+			if name != filename {
+				continue
+			}
+			v, _ := checkedFiles.LoadOrStore(p.ID, &checkedPackage{})
+			c := v.(*checkedPackage)
+			c.once.Do(func() {
+				c.uses = checkUses(p)
+			})
+			return c.uses[filename], nil
+		}
+	}
+	return nil, nil
+}
+
+// checkUses type-checks the package and finds uses of imports of its files
+func checkUses(p *packages.Package) map[string]map[string]bool {
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+	}
+	_, files, names, failed := checkPackage(p, info)
+
+	used := make(map[types.Object]bool)
+	for _, obj := range info.Uses {
+		if obj, ok := obj.(*types.PkgName); ok {
+			used[obj] = true
+		}
+	}
+
+	ret := make(map[string]map[string]bool, len(files))
+	for i, f := range files {
+		uses := make(map[string]bool)
+		for _, s := range f.Imports {
+			if failed[unquote(s.Path.Value)] {
+				continue
+			}
+			var (
+				alias string
+				obj   types.Object
+			)
+			if s.Name != nil {
+				alias = s.Name.Name
+				obj = info.Defs[s.Name]
+			} else {
+				obj = info.Implicits[s]
+			}
+			if obj != nil {
+				uses[specKey(alias, s.Path.Value)] = used[obj]
+			}
+		}
+		ret[names[i]] = uses
+	}
+	return ret
+}
+
+// checkDep returns the type-checked dependency, it is nil if the package
+// can't be loaded
+func checkDep(p *packages.Package) *types.Package {
+	v, _ := checkedDeps.LoadOrStore(p.ID, &checkedPackage{})
+	c := v.(*checkedPackage)
+	c.once.Do(func() {
+		if len(p.GoFiles) > 0 {
+			c.types, _, _, _ = checkPackage(p, nil)
+		}
+	})
+	return c.types
+}
+
+// checkPackage type-checks the package from source ignoring type and syntax
+// errors, function bodies are checked only if info is passed. It returns
+// parsed files with their names and paths of imports that can't be loaded
+func checkPackage(p *packages.Package, info *types.Info) (t *types.Package, files []*ast.File, names []string, failed map[string]bool) {
+	fset := token.NewFileSet()
+	for _, name := range p.GoFiles {
+		if f, _ := parser.ParseFile(fset, name, nil, 0); f != nil {
+			files = append(files, f)
+			names = append(names, name)
+		}
+	}
+
+	failed = make(map[string]bool)
+	conf := &types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == "unsafe" {
+				return types.Unsafe, nil
+			}
+			if imported := p.Imports[path]; imported != nil {
+				if t := checkDep(imported); t != nil {
+					return t, nil
+				}
+			}
+			failed[path] = true
+			return nil, errors.New("package can't be loaded")
+		}),
+		IgnoreFuncBodies: info == nil,
+		FakeImportC:      true,
+		Error:            func(error) {},
+	}
+	t, _ = conf.Check(p.PkgPath, fset, files, info)
+	return t, files, names, failed
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
@@ -0,0 +1,85 @@
+package gci
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// unusedImports returns edits removing imports of the block that are not
+// referenced in the file like goimports does. The package of the file is
+// type-checked from source without network access. Blank, dot and cgo imports
+// are kept as well as imports of packages that can't be loaded. Files that
+// can't be parsed or aren't part of any package for the current build
+// configuration are not changed
+func unusedImports(filename string, src []byte) (*importEdits, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, nil
+	}
+
+	var decl *ast.GenDecl
+	for _, d := range f.Decls {
+		if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.IMPORT && d.Lparen.IsValid() {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		return nil, nil
+	}
+
+	var (
+		e    importEdits
+		uses map[string]bool
+	)
+	for _, s := range decl.Specs {
+		s := s.(*ast.ImportSpec)
+		path := unquote(s.Path.Value)
+
+		var alias string
+		if s.Name != nil {
+			alias = s.Name.Name
+		}
+		if path == "C" || alias == "_" || alias == "." {
+			continue
+		}
+
+		if uses == nil {
+			abs, err := filepath.Abs(filename)
+			if err != nil {
+				return nil, err
+			}
+			if uses, err = importUses(abs); err != nil {
+				return nil, err
+			}
+			if uses == nil {
+				return nil, nil
+			}
+		}
+		key := specKey(alias, s.Path.Value)
+		if used, ok := uses[key]; !ok || used {
+			continue
+		}
+
+		if e.removed == nil {
+			e.removed = make(map[string]bool)
+		}
+		e.removed[key] = true
+	}
+	if e.removed == nil {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// importPathBase returns the last element of the import path, it is the name
+// of most packages
+func importPathBase(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
@@ -0,0 +1,93 @@
+package gci
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+
+	// packages are loaded once, so all files are written before pruning
+	dir := newModule(t, map[string]string{
+		"b.go":       "package main\n\nimport (\n\t\"os\"\n\t\"strings\"\n)\n\nfunc f() {\n\tstrings := struct{ Title string }{}\n\t_ = strings.Title\n\t_ = os.Args\n}\n",
+		"a_test.go":  "package main\n\nimport (\n\t\"os\"\n\t\"testing\"\n)\n\nfunc TestA(t *testing.T) {}\n",
+		"ignored.go": "//go:build ignore\n// +build ignore\n\npackage main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nvar _ = fmt.Sprint\n",
+		"a.go": `package main
+
+import (
+	// doc of os
+	"os" // os
+	"fmt"
+	str "strings"
+	s "sort"
+	_ "embed"
+	. "math"
+	"example.com/m/v2"
+)
+
+func main() {
+	fmt.Println(str.ToUpper("a"), Pi)
+	lib.F()
+}
+`,
+	})
+	filename := filepath.Join(dir, "a.go")
+
+	src, res, err := Run(filename, &FlagSet{Prune: true, VerifyIdempotent: true})
+	require.Nil(t, err)
+	require.NotNil(t, src)
+	require.Equal(t, `package main
+
+import (
+	_ "embed"
+	"fmt"
+	. "math"
+	str "strings"
+
+	"example.com/m/v2"
+)
+
+func main() {
+	fmt.Println(str.ToUpper("a"), Pi)
+	lib.F()
+}
+`, string(res))
+
+	// the shadowed package is not used
+	_, res, err = Run(filepath.Join(dir, "b.go"), &FlagSet{Prune: true})
+	require.Nil(t, err)
+	require.Equal(t, "package main\n\nimport (\n\t\"os\"\n)\n\nfunc f() {\n\tstrings := struct{ Title string }{}\n\t_ = strings.Title\n\t_ = os.Args\n}\n", string(res))
+
+	_, res, err = Run(filepath.Join(dir, "a_test.go"), &FlagSet{Prune: true})
+	require.Nil(t, err)
+	require.Equal(t, "package main\n\nimport (\n\t\"testing\"\n)\n\nfunc TestA(t *testing.T) {}\n", string(res))
+
+	// files excluded from the build can't be type-checked, they are kept
+	src, res, err = Run(filepath.Join(dir, "ignored.go"), &FlagSet{Prune: true})
+	require.Nil(t, err)
+	require.NotNil(t, src)
+	require.Nil(t, res)
+}
+
+func TestPackageNamesCache(t *testing.T) {
+	t.Parallel()
+
+	dir := newModule(t, map[string]string{
+		"a.go":      "package a\n\nimport \"os\"\n\nvar _ = os.Args\n",
+		"a_test.go": "package a_test\n\nimport \"strings\"\n\nvar _ = strings.ToUpper\n",
+	})
+
+	// files of the directory share names loaded once
+	names, err := packageNames(filepath.Join(dir, "a.go"))
+	require.Nil(t, err)
+	require.Equal(t, "os", names["os"])
+	require.Equal(t, "strings", names["strings"])
+
+	testNames, err := packageNames(filepath.Join(dir, "a_test.go"))
+	require.Nil(t, err)
+	require.Equal(t, reflect.ValueOf(names).Pointer(), reflect.ValueOf(testNames).Pointer())
+}
@@ -0,0 +1,297 @@
+package gci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Status of a processed file
+type Status string
+
+const (
+	StatusUnchanged Status = "unchanged"
+	StatusChanged   Status = "changed"
+	StatusSkipped   Status = "skipped"
+	StatusError     Status = "error"
+)
+
+// Result holds everything known about a processed file
+type Result struct {
+	Filename string
+	Status   Status
+	// Reason explains why the file is skipped
+	Reason string
+	Err    error
+
+	// Formatted is equal to Original if the file is unchanged
+	Original, Formatted []byte
+	// Moves lists imports moved by formatting
+	Moves []Move
+	// Violations lists rules violated by imports of the original file
+	Violations []Violation
+	// Diff is an unified diff, it is computed only if the reporter needs it
+	Diff []byte
+}
+
+func (r *Result) skip(reason string) *Result {
+	r.Status = StatusSkipped
+	r.Reason = reason
+	return r
+}
+
+func (r *Result) fail(err error) *Result {
+	r.Status = StatusError
+	r.Err = err
+	return r
+}
+
+// Summary counts processed files by their status
+type Summary struct {
+	Files     int `json:"files"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"`
+	Errors    int `json:"errors"`
+}
+
+func (s *Summary) add(r *Result) {
+	s.Files++
+	switch r.Status {
+	case StatusChanged:
+		s.Changed++
+	case StatusUnchanged:
+		s.Unchanged++
+	case StatusSkipped:
+		s.Skipped++
+	case StatusError:
+		s.Errors++
+	}
+}
+
+// Reporter prints results of processed files
+type Reporter interface {
+	// Report is called for every processed file in the deterministic order
+	Report(out io.Writer, r *Result) error
+	// Finish is called once all files are reported
+	Finish(out io.Writer) error
+	// Needs reports which optional fields of results are used, they are
+	// computed only if needed
+	Needs() (diff, violations, moves bool)
+}
+
+// NewReporter returns a reporter for the output format: text, json, sarif,
+// checkstyle, junit or github
+func NewReporter(format string, set *FlagSet) (Reporter, error) {
+	switch format {
+	case "", "text":
+		return &textReporter{set: set}, nil
+	case "json":
+		return &jsonReporter{}, nil
+	case "sarif":
+		return &sarifReporter{}, nil
+	case "checkstyle":
+		return &checkstyleReporter{}, nil
+	case "junit":
+		return &junitReporter{}, nil
+	case "github":
+		return githubReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func (set *FlagSet) reporter() Reporter {
+	if set.Reporter != nil {
+		return set.Reporter
+	}
+	return &textReporter{set: set}
+}
+
+// report passes the result to the reporter, it must not be called concurrently
+func (set *FlagSet) report(out io.Writer, r *Result) error {
+	if set.Summary != nil {
+		set.Summary.add(r)
+	}
+	return set.reporter().Report(out, r)
+}
+
+// needDiff reports whether results must contain diffs
+func (set *FlagSet) needDiff() bool {
+	diff, _, _ := set.reporter().Needs()
+	return diff
+}
+
+// needViolations reports whether results must contain violations
+func (set *FlagSet) needViolations() bool {
+	_, violations, _ := set.reporter().Needs()
+	return violations
+}
+
+// needMoves reports whether results must contain moves
+func (set *FlagSet) needMoves() bool {
+	_, _, moves := set.reporter().Needs()
+	return moves
+}
+
+// textReporter prints formatted files or diffs depending on flags, skipped
+// files are noted on stderr to keep the printed source valid
+type textReporter struct {
+	set *FlagSet
+	// stderr is os.Stderr if it is nil
+	stderr io.Writer
+}
+
+func (t *textReporter) Report(out io.Writer, r *Result) error {
+	switch r.Status {
+	case StatusSkipped:
+		fmt.Fprintf(t.errOut(), "skip file %s since %s\n", r.Filename, r.Reason)
+		return nil
+	case StatusError:
+		return nil
+	}
+
+	if r.Status == StatusChanged && *t.set.DoDiff {
+		fmt.Fprintf(out, "diff -u %s %s\n", filepath.ToSlash(r.Filename+".orig"), filepath.ToSlash(r.Filename))
+		if _, err := out.Write(r.Diff); err != nil {
+			return fmt.Errorf("failed to write: %v", err)
+		}
+	}
+	if !*t.set.DoWrite && !*t.set.DoDiff {
+		if _, err := out.Write(r.Formatted); err != nil {
+			return fmt.Errorf("failed to write: %v", err)
+		}
+	}
+	return nil
+}
+
+func (t *textReporter) errOut() io.Writer {
+	if t.stderr != nil {
+		return t.stderr
+	}
+	return os.Stderr
+}
+
+func (t *textReporter) Finish(io.Writer) error {
+	return nil
+}
+
+func (t *textReporter) Needs() (diff, violations, moves bool) {
+	// flags are not set if the file is only linted
+	return t.set.DoDiff != nil && *t.set.DoDiff, false, false
+}
+
+// NewListReporter returns a reporter printing names of files that differ from
+// the formatted ones
+func NewListReporter() Reporter {
+	return listReporter{}
+}
+
+type listReporter struct{}
+
+func (listReporter) Report(out io.Writer, r *Result) error {
+	if r.Status != StatusChanged {
+		return nil
+	}
+	_, err := fmt.Fprintln(out, r.Filename)
+	return err
+}
+
+func (listReporter) Finish(io.Writer) error {
+	return nil
+}
+
+func (listReporter) Needs() (diff, violations, moves bool) {
+	return false, false, false
+}
+
+// NewCheckReporter returns a reporter printing violations of files that differ
+// from the formatted ones
+func NewCheckReporter() Reporter {
+	return checkReporter{}
+}
+
+type checkReporter struct{}
+
+func (checkReporter) Report(out io.Writer, r *Result) error {
+	if r.Status != StatusChanged {
+		return nil
+	}
+	if len(r.Violations) == 0 {
+		_, err := fmt.Fprintf(out, "%s: imports are not formatted\n", r.Filename)
+		return err
+	}
+	for _, v := range r.Violations {
+		if _, err := fmt.Fprintf(out, "%s:%d:%d: %s (%s)\n", r.Filename, v.Line, v.Column, v.Message, v.Kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (checkReporter) Finish(io.Writer) error {
+	return nil
+}
+
+func (checkReporter) Needs() (diff, violations, moves bool) {
+	return false, true, false
+}
+
+// jsonReporter prints a JSON record per line for every file and
+// the summary record at the end
+type jsonReporter struct {
+	summary Summary
+}
+
+type jsonFileRecord struct {
+	Type   string `json:"type"`
+	Path   string `json:"path"`
+	Status Status `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Moves  []Move `json:"moves,omitempty"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+type jsonSummaryRecord struct {
+	Type string `json:"type"`
+	Summary
+}
+
+func (j *jsonReporter) Report(out io.Writer, r *Result) error {
+	j.summary.add(r)
+
+	record := jsonFileRecord{
+		Type:   "file",
+		Path:   filepath.ToSlash(r.Filename),
+		Status: r.Status,
+		Reason: r.Reason,
+		Moves:  r.Moves,
+		Diff:   string(r.Diff),
+	}
+	if r.Err != nil {
+		record.Error = r.Err.Error()
+	}
+	return writeJSON(out, record)
+}
+
+func (j *jsonReporter) Finish(out io.Writer) error {
+	return writeJSON(out, jsonSummaryRecord{Type: "summary", Summary: j.summary})
+}
+
+func (j *jsonReporter) Needs() (diff, violations, moves bool) {
+	return true, false, true
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package gci
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONReporter(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	reporter, err := NewReporter("json", nil)
+	require.Nil(err)
+
+	newBool := func(v bool) *bool { return &v }
+	flagSet := &FlagSet{
+		LocalFlag: "github.com/local/repo",
+		DoWrite:   newBool(false),
+		DoDiff:    newBool(false),
+		Reporter:  reporter,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, filename := range []string{"testdata/1.in.go", "testdata/1.want.go", "std.go"} {
+		require.Nil(ProcessFile(filename, buf, flagSet))
+	}
+	require.Nil(reporter.Finish(buf))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), linebreak), linebreak)
+	require.Len(lines, 4)
+
+	var changed jsonFileRecord
+	require.Nil(json.Unmarshal([]byte(lines[0]), &changed))
+	require.Equal(StatusChanged, changed.Status)
+	require.Equal([]Move{
+		{Path: "fmt", OldSection: "standard", NewSection: "standard", OldLine: 5, NewLine: 6},
+		{Path: "embed", Name: "_", OldSection: "standard", NewSection: "standard", OldLine: 6, NewLine: 4},
+		{Path: "github.com/local/repo/pkg2", OldSection: "standard", NewSection: "local", OldLine: 8, NewLine: 13},
+		{Path: "github.com/local/repo/pkg1", OldSection: "standard", NewSection: "local", OldLine: 10, NewLine: 11},
+		{Path: "github.com/jackc/pgx/v4/stdlib", Name: "_", OldSection: "standard", NewSection: "default", OldLine: 11, NewLine: 8},
+	}, changed.Moves)
+	require.True(strings.HasPrefix(changed.Diff, "--- testdata/1.in.go.orig"))
+
+	require.JSONEq(`{"type":"file","path":"testdata/1.want.go","status":"unchanged"}`, lines[1])
+	require.JSONEq(`{"type":"file","path":"std.go","status":"skipped","reason":"it is generated"}`, lines[2])
+	require.JSONEq(`{"type":"summary","files":3,"changed":1,"unchanged":1,"skipped":1,"errors":0}`, lines[3])
+}
+
+func TestSARIFReporter(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	reporter, err := NewReporter("sarif", nil)
+	require.Nil(err)
+
+	newBool := func(v bool) *bool { return &v }
+	flagSet := &FlagSet{
+		LocalFlag: "github.com/local/repo",
+		DoWrite:   newBool(false),
+		DoDiff:    newBool(false),
+		Reporter:  reporter,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, filename := range []string{"testdata/1.in.go", "testdata/1.want.go"} {
+		require.Nil(ProcessFile(filename, buf, flagSet))
+	}
+	require.Empty(buf.String())
+	require.Nil(reporter.Finish(buf))
+
+	var log sarifLog
+	require.Nil(json.Unmarshal(buf.Bytes(), &log))
+	require.Equal("2.1.0", log.Version)
+	require.Len(log.Runs, 1)
+	require.Len(log.Runs[0].Tool.Driver.Rules, len(ViolationKinds))
+
+	results := log.Runs[0].Results
+	require.NotEmpty(results)
+	for _, r := range results {
+		require.Equal("testdata/1.in.go", r.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	}
+
+	require.Equal("wrong-order", results[0].RuleID)
+	require.Equal(`"embed" should come before "fmt"`, results[0].Message.Text)
+	require.Equal(sarifRegion{StartLine: 6, StartColumn: 2, EndLine: 6, EndColumn: 11}, results[0].Locations[0].PhysicalLocation.Region)
+
+	replacement := results[0].Fixes[0].ArtifactChanges[0].Replacements[0]
+	require.Equal(sarifRegion{StartLine: 4, StartColumn: 1, EndLine: 12, EndColumn: 1}, replacement.DeletedRegion)
+
+	want, err := ioutil.ReadFile("testdata/1.want.go")
+	require.Nil(err)
+	require.Contains(string(want), replacement.InsertedContent.Text)
+}
+
+func TestXMLReporters(t *testing.T) {
+	t.Parallel()
+
+	newBool := func(v bool) *bool { return &v }
+	process := func(t *testing.T, format string) []byte {
+		reporter, err := NewReporter(format, nil)
+		require.Nil(t, err)
+
+		flagSet := &FlagSet{
+			LocalFlag: "github.com/local/repo",
+			DoWrite:   newBool(false),
+			DoDiff:    newBool(false),
+			Reporter:  reporter,
+		}
+
+		buf := bytes.NewBuffer(nil)
+		for _, filename := range []string{"testdata/1.in.go", "testdata/1.want.go", "std.go"} {
+			require.Nil(t, ProcessFile(filename, buf, flagSet))
+		}
+		require.Nil(t, reporter.Finish(buf))
+		require.True(t, strings.HasPrefix(buf.String(), xml.Header))
+		return buf.Bytes()
+	}
+
+	t.Run("checkstyle", func(t *testing.T) {
+		var output checkstyleOutput
+		require.Nil(t, xml.Unmarshal(process(t, "checkstyle"), &output))
+
+		require.Len(t, output.Files, 1)
+		require.Equal(t, "testdata/1.in.go", output.Files[0].Name)
+		require.Equal(t, checkstyleError{
+			Line:     6,
+			Column:   2,
+			Severity: "error",
+			Message:  `"embed" should come before "fmt"`,
+			Source:   "gci.wrong-order",
+		}, output.Files[0].Errors[0])
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		var output junitTestSuites
+		require.Nil(t, xml.Unmarshal(process(t, "junit"), &output))
+
+		require.Len(t, output.Suites, 1)
+		suite := output.Suites[0]
+		require.Equal(t, 3, suite.Tests)
+		require.Equal(t, 1, suite.Failures)
+		require.Equal(t, 1, suite.Skipped)
+		require.Len(t, suite.TestCases, 3)
+
+		failure := suite.TestCases[0].Failure
+		require.NotNil(t, failure)
+		require.True(t, strings.HasPrefix(failure.Text, `testdata/1.in.go:6:2: "embed" should come before "fmt"`))
+		require.Contains(t, failure.Text, "--- testdata/1.in.go.orig")
+
+		require.Nil(t, suite.TestCases[1].Failure)
+		require.Equal(t, "it is generated", suite.TestCases[2].Skipped.Message)
+	})
+}
+
+func TestGithubReporter(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	reporter, err := NewReporter("github", nil)
+	require.Nil(err)
+
+	newBool := func(v bool) *bool { return &v }
+	flagSet := &FlagSet{
+		LocalFlag: "github.com/local/repo",
+		DoWrite:   newBool(false),
+		DoDiff:    newBool(false),
+		Reporter:  reporter,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, filename := range []string{"testdata/1.in.go", "testdata/1.want.go", "std.go"} {
+		require.Nil(ProcessFile(filename, buf, flagSet))
+	}
+	require.Nil(reporter.Finish(buf))
+
+	require.Equal("::error file=testdata/1.in.go,line=6,endLine=6,title=gci::imports are not formatted by gci: \"embed\" should come before \"fmt\"\n", buf.String())
+	require.Equal("a%3Ab%2Cc%0Ad%25", escapeGithubProperty("a:b,c\nd%"))
+}
+
+func TestListAndCheckReporters(t *testing.T) {
+	t.Parallel()
+
+	newBool := func(v bool) *bool { return &v }
+	process := func(t *testing.T, reporter Reporter) (string, Summary) {
+		var summary Summary
+		flagSet := &FlagSet{
+			LocalFlag: "github.com/local/repo",
+			DoWrite:   newBool(false),
+			DoDiff:    newBool(false),
+			Reporter:  reporter,
+			Summary:   &summary,
+		}
+
+		buf := bytes.NewBuffer(nil)
+		for _, filename := range []string{"testdata/1.in.go", "testdata/1.want.go", "std.go"} {
+			require.Nil(t, ProcessFile(filename, buf, flagSet))
+		}
+		require.Nil(t, reporter.Finish(buf))
+		return buf.String(), summary
+	}
+
+	t.Run("list", func(t *testing.T) {
+		out, summary := process(t, NewListReporter())
+		require.Equal(t, "testdata/1.in.go\n", out)
+		require.Equal(t, Summary{Files: 3, Changed: 1, Unchanged: 1, Skipped: 1}, summary)
+	})
+
+	t.Run("check", func(t *testing.T) {
+		out, _ := process(t, NewCheckReporter())
+		require.True(t, strings.HasPrefix(out, "testdata/1.in.go:6:2: \"embed\" should come before \"fmt\" (wrong-order)\n"))
+		require.Equal(t, 7, strings.Count(out, linebreak))
+	})
+}
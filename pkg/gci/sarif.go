@@ -0,0 +1,154 @@
+package gci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// types of the SARIF 2.1.0 log, only used fields are declared,
+// see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+		Fixes     []sarifFix      `json:"fixes,omitempty"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndLine     int `json:"endLine"`
+		EndColumn   int `json:"endColumn"`
+	}
+	sarifFix struct {
+		Description     sarifMessage          `json:"description"`
+		ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+	}
+	sarifArtifactChange struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Replacements     []sarifReplacement    `json:"replacements"`
+	}
+	sarifReplacement struct {
+		DeletedRegion   sarifRegion  `json:"deletedRegion"`
+		InsertedContent sarifMessage `json:"insertedContent"`
+	}
+)
+
+// sarifReporter collects violations of all files and prints a SARIF log
+type sarifReporter struct {
+	results []sarifResult
+}
+
+func (s *sarifReporter) Report(_ io.Writer, r *Result) error {
+	if len(r.Violations) == 0 {
+		return nil
+	}
+
+	uri := filepath.ToSlash(r.Filename)
+
+	var fixes []sarifFix
+	if fix, ok := blockFix(r); ok {
+		fixes = []sarifFix{{
+			Description: sarifMessage{Text: "Reorder imports"},
+			ArtifactChanges: []sarifArtifactChange{{
+				ArtifactLocation: sarifArtifactLocation{URI: uri},
+				Replacements: []sarifReplacement{{
+					DeletedRegion:   sarifRegion{StartLine: fix.StartLine, StartColumn: 1, EndLine: fix.EndLine, EndColumn: 1},
+					InsertedContent: sarifMessage{Text: fix.Text},
+				}},
+			}},
+		}}
+	}
+
+	for _, v := range r.Violations {
+		s.results = append(s.results, sarifResult{
+			RuleID:  string(v.Kind),
+			Level:   "error",
+			Message: sarifMessage{Text: v.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: uri},
+					Region:           sarifRegion{StartLine: v.Line, StartColumn: v.Column, EndLine: v.Line, EndColumn: v.EndColumn},
+				},
+			}},
+			Fixes: fixes,
+		})
+	}
+	return nil
+}
+
+func (s *sarifReporter) Needs() (diff, violations, moves bool) {
+	return false, true, false
+}
+
+func (s *sarifReporter) Finish(out io.Writer) error {
+	rules := make([]sarifRule, 0, len(ViolationKinds))
+	for _, k := range ViolationKinds {
+		rules = append(rules, sarifRule{ID: string(k.Kind), ShortDescription: sarifMessage{Text: k.Description}})
+	}
+
+	results := s.results
+	if results == nil {
+		// SARIF requires an array even if there are no results
+		results = []sarifResult{}
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "gci",
+				InformationURI: "https://github.com/daixiang0/gci",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write: %v", err)
+	}
+	return nil
+}
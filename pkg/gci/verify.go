@@ -0,0 +1,253 @@
+package gci
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// importDecl is a parsed file with its import block
+type importDecl struct {
+	fset *token.FileSet
+	file *ast.File
+	decl *ast.GenDecl
+}
+
+func parseImportDecl(src []byte) (*importDecl, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &importDecl{fset: fset, file: f}
+	for _, decl := range f.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT && decl.Lparen.IsValid() {
+			d.decl = decl
+			break
+		}
+	}
+	return d, nil
+}
+
+func (d *importDecl) offset(pos token.Pos) int {
+	return d.fset.Position(pos).Offset
+}
+
+// specs returns distinct imports, exact duplicates may be removed
+func (d *importDecl) specs(e *importEdits) []string {
+	seen := make(map[string]bool)
+	var ret []string
+	for _, s := range d.decl.Specs {
+		s := s.(*ast.ImportSpec)
+		spec := specKey(e.name(importSpecName(s), s.Path.Value), s.Path.Value)
+		if !seen[spec] && !e.isRemoved(importSpecName(s), s.Path.Value) {
+			seen[spec] = true
+			ret = append(ret, spec)
+		}
+	}
+	if e != nil {
+		for _, added := range e.added {
+			if spec := specKey(added.name, added.path); !seen[spec] {
+				seen[spec] = true
+				ret = append(ret, spec)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// lineComments returns imports with their same line comments, the same line
+// comment must stay with its import
+func (d *importDecl) lineComments(e *importEdits) map[string]bool {
+	ret := make(map[string]bool)
+	for _, s := range d.decl.Specs {
+		s := s.(*ast.ImportSpec)
+		if s.Comment != nil {
+			spec := specKey(e.name(importSpecName(s), s.Path.Value), s.Path.Value)
+			ret[spec+blank+commentText(s.Comment, nil)] = true
+		}
+	}
+	return ret
+}
+
+func importSpecName(s *ast.ImportSpec) string {
+	if s.Name != nil {
+		return s.Name.Name
+	}
+	return ""
+}
+
+// removedComments returns comments of removed imports
+func (d *importDecl) removedComments(e *importEdits) map[*ast.CommentGroup]bool {
+	ret := make(map[*ast.CommentGroup]bool)
+	for _, s := range d.decl.Specs {
+		s := s.(*ast.ImportSpec)
+		if e.isRemoved(importSpecName(s), s.Path.Value) {
+			ret[s.Doc] = true
+			ret[s.Comment] = true
+		}
+	}
+	return ret
+}
+
+// docs returns doc comments of imports without ignored comments, duplicates
+// of an import may have several ones
+func (d *importDecl) docs(ignored map[string]bool, e *importEdits) map[string][]string {
+	ret := make(map[string][]string)
+	for _, s := range d.decl.Specs {
+		s := s.(*ast.ImportSpec)
+		if s.Doc == nil || e.isRemoved(importSpecName(s), s.Path.Value) {
+			continue
+		}
+		if text := commentText(s.Doc, ignored); text != "" {
+			ret[s.Path.Value] = append(ret[s.Path.Value], text)
+		}
+	}
+	return ret
+}
+
+// comments returns all comments inside of the import block except ignored
+// ones and comments of removed imports
+func (d *importDecl) comments(ignored map[string]bool, e *importEdits) []string {
+	removed := d.removedComments(e)
+
+	var ret []string
+	for _, group := range d.file.Comments {
+		if group.Pos() > d.decl.Lparen && group.End() < d.decl.Rparen && !removed[group] {
+			for _, c := range group.List {
+				if !ignored[c.Text] {
+					ret = append(ret, c.Text)
+				}
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func commentText(group *ast.CommentGroup, ignored map[string]bool) string {
+	texts := make([]string, 0, len(group.List))
+	for _, c := range group.List {
+		if !ignored[c.Text] {
+			texts = append(texts, c.Text)
+		}
+	}
+	return strings.Join(texts, linebreak)
+}
+
+// verify checks that formatting only reorders and regroups imports: res must
+// have the same imports and comments and the same code outside of the import
+// block as src, section headers may be added or removed and imports may be
+// changed by edits. Files that can't be parsed are not changed since the
+// result can't be verified
+func verify(src, res []byte, set *FlagSet, edits *importEdits) error {
+	before, err := parseImportDecl(src)
+	if err != nil {
+		return fmt.Errorf("file is not valid Go code: %v", err)
+	}
+	if before.decl == nil {
+		// "import (" is found in a comment or a string
+		return errors.New("there is no import block")
+	}
+	after, err := parseImportDecl(res)
+	if err != nil {
+		return fmt.Errorf("result is not valid Go code: %v", err)
+	}
+	if after.decl == nil {
+		return errors.New("import block is lost")
+	}
+
+	if !bytes.Equal(src[:before.offset(before.decl.Lparen)], res[:after.offset(after.decl.Lparen)]) ||
+		!bytes.Equal(src[before.offset(before.decl.Rparen):], res[after.offset(after.decl.Rparen):]) {
+		return errors.New("code outside of the import block is changed")
+	}
+
+	if diff := diffStrings(before.specs(edits), after.specs(nil)); diff != "" {
+		return fmt.Errorf("imports are changed: %s", diff)
+	}
+
+	beforeComments := before.lineComments(edits)
+	for spec := range after.lineComments(nil) {
+		if !beforeComments[spec] {
+			return fmt.Errorf("same line comment is changed: %s", spec)
+		}
+	}
+
+	ignored := make(map[string]bool)
+	for _, header := range set.sectionHeaders() {
+		ignored[header] = true
+	}
+
+	// doc comments of removed duplicates are merged
+	afterDocs := after.docs(ignored, nil)
+	for path, docs := range before.docs(ignored, edits) {
+		got := strings.Join(afterDocs[path], linebreak)
+		for _, doc := range docs {
+			if !strings.Contains(got, doc) {
+				return fmt.Errorf("doc comment of %s is changed: %q is expected, got %q", path, doc, got)
+			}
+		}
+	}
+
+	if diff := diffStrings(before.comments(ignored, edits), after.comments(ignored, nil)); diff != "" {
+		return fmt.Errorf("comments are changed: %s", diff)
+	}
+	return nil
+}
+
+// diffStrings describes the difference of sorted lists
+func diffStrings(before, after []string) string {
+	var removed, added []string
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case j == len(after) || (i < len(before) && before[i] < after[j]):
+			removed = append(removed, before[i])
+			i++
+		case i == len(before) || after[j] < before[i]:
+			added = append(added, after[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+
+	var msgs []string
+	if len(removed) > 0 {
+		msgs = append(msgs, fmt.Sprintf("%q removed", removed))
+	}
+	if len(added) > 0 {
+		msgs = append(msgs, fmt.Sprintf("%q added", added))
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// verifyIdempotent formats res, the result of the first pass over src, once
+// more and returns an error with diffs of both passes if it is changed
+func verifyIdempotent(filename string, src, res []byte, set *FlagSet, edits *importEdits) error {
+	again, ok := formatOnce(res, set, edits)
+	if !ok {
+		return fmt.Errorf("%s: formatting is not idempotent: the import block is lost after the first pass", filename)
+	}
+	if bytes.Equal(res, again) {
+		return nil
+	}
+
+	first, err := diff(src, res, filename)
+	if err != nil {
+		return fmt.Errorf("failed to diff: %v", err)
+	}
+	second, err := diff(res, again, filename)
+	if err != nil {
+		return fmt.Errorf("failed to diff: %v", err)
+	}
+	return fmt.Errorf("%s: formatting is not idempotent, the second pass changes the result\nfirst pass:\n%s\nsecond pass:\n%s", filename, first, second)
+}
@@ -0,0 +1,211 @@
+package gci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	const src = `package main
+
+import (
+	// doc
+	"os"
+	"fmt" // fmt
+	/* block */
+)
+
+func main() {}
+`
+
+	tests := []struct {
+		desc string
+		src  string
+		res  string
+		//
+		wantErr string
+	}{
+		{
+			desc: "reordered",
+			src:  src,
+			res: `package main
+
+import (
+	"fmt" // fmt
+	// doc
+	"os"
+	/* block */
+)
+
+func main() {}
+`,
+		},
+		{
+			desc:    "invalid original",
+			src:     "package main\nimport (\n",
+			res:     "package",
+			wantErr: "file is not valid Go code",
+		},
+		{
+			desc:    "no import block",
+			src:     "package main\n\n// import (\n// )\n",
+			res:     "package main\n\n// import (\n\n// )\n",
+			wantErr: "there is no import block",
+		},
+		{
+			desc:    "invalid result",
+			src:     src,
+			res:     "package main\nimport (\n",
+			wantErr: "result is not valid Go code",
+		},
+		{
+			desc: "changed code",
+			src:  src,
+			res: `package main
+
+import (
+	"fmt" // fmt
+	// doc
+	"os"
+	/* block */
+)
+
+func main() { os.Exit(1) }
+`,
+			wantErr: "code outside of the import block is changed",
+		},
+		{
+			desc: "lost import",
+			src:  src,
+			res: `package main
+
+import (
+	"fmt" // fmt
+	/* block */
+	// doc
+)
+
+func main() {}
+`,
+			wantErr: `imports are changed: ["\"os\""] removed`,
+		},
+		{
+			desc: "moved comment",
+			src:  src,
+			res: `package main
+
+import (
+	"fmt"
+	// doc
+	"os" // fmt
+	/* block */
+)
+
+func main() {}
+`,
+			wantErr: `same line comment is changed: "os" // fmt`,
+		},
+		{
+			desc: "detached doc",
+			src:  src,
+			res: `package main
+
+import (
+	// doc
+
+	"fmt" // fmt
+	"os"
+	/* block */
+)
+
+func main() {}
+`,
+			wantErr: `doc comment of "os" is changed`,
+		},
+		{
+			desc: "removed duplicate",
+			src: `package main
+
+import (
+	"os"
+	"fmt"
+	"os"
+)
+`,
+			res: `package main
+
+import (
+	"fmt"
+	"os"
+)
+`,
+		},
+		{
+			desc: "lost comment",
+			src:  src,
+			res: `package main
+
+import (
+	"fmt" // fmt
+	// doc
+	"os"
+)
+
+func main() {}
+`,
+			wantErr: `comments are changed: ["/* block */"] removed`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := verify([]byte(test.src), []byte(test.res), &FlagSet{}, nil)
+			if test.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.wantErr)
+		})
+	}
+}
+
+// requireIdempotent fails the test if formatting of src is changed by the
+// second pass
+func requireIdempotent(t *testing.T, set *FlagSet, src []byte) {
+	t.Helper()
+
+	res, ok := formatOnce(src, set, nil)
+	if !ok {
+		return
+	}
+	require.NoError(t, verifyIdempotent("src.go", src, res, set, nil))
+}
+
+func TestVerifyIdempotent(t *testing.T) {
+	t.Parallel()
+
+	set := &FlagSet{LocalFlag: "github.com/local/repo"}
+	src := []byte(`package main
+
+import (
+	"os"
+	"fmt"
+)
+`)
+	requireIdempotent(t, set, src)
+
+	// the source is passed as the result of the first pass, so the second
+	// pass changes it
+	err := verifyIdempotent("src.go", src, src, set, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "src.go: formatting is not idempotent")
+	require.Contains(t, err.Error(), "second pass:\n--- src.go.orig")
+	require.Contains(t, err.Error(), "+\t\"os\"\n")
+}
@@ -0,0 +1,205 @@
+package gci
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// ViolationKind identifies a rule violated by an import
+type ViolationKind string
+
+const (
+	WrongSection        ViolationKind = "wrong-section"
+	WrongOrder          ViolationKind = "wrong-order"
+	MissingBlankLine    ViolationKind = "missing-blank-line"
+	DuplicateImport     ViolationKind = "duplicate-import"
+	UnexpectedBlankLine ViolationKind = "unexpected-blank-line"
+	RedundantAlias      ViolationKind = "redundant-alias"
+	ConflictingName     ViolationKind = "conflicting-name"
+)
+
+// ViolationKinds lists all kinds with their descriptions
+var ViolationKinds = []struct {
+	Kind        ViolationKind
+	Description string
+}{
+	{WrongSection, "Import is in the wrong section"},
+	{WrongOrder, "Imports are not sorted"},
+	{MissingBlankLine, "Sections are not separated by a blank line"},
+	{DuplicateImport, "Package is imported more than once"},
+	{UnexpectedBlankLine, "Section is split by a blank line"},
+	{RedundantAlias, "Import is named as the package"},
+	{ConflictingName, "Name of the import is used by another import"},
+}
+
+// Violation describes why an import must be moved
+type Violation struct {
+	Kind    ViolationKind
+	Message string
+	// Path is an unquoted path of the import
+	Path string
+	// position of the import spec
+	Line, Column, EndColumn int
+}
+
+// lint returns violations of the import block of src in the order of imports
+func lint(src []byte, localFlag string) []Violation {
+	specs, err := parseImports(src)
+	if err != nil || len(specs) == 0 {
+		return nil
+	}
+
+	var (
+		ret      []Violation
+		sections = groupSections(specs, localFlag)
+		seen     = make(map[string]bool)
+		// names are imports by their explicit names
+		names = make(map[string]importSpec)
+		// named are the first imports of paths with names usable in the
+		// file, blank and dot imports may be added to them
+		named = make(map[string]importSpec)
+	)
+	for i, spec := range specs {
+		add := func(kind ViolationKind, format string, args ...interface{}) {
+			ret = append(ret, Violation{
+				Kind:      kind,
+				Message:   fmt.Sprintf(format, args...),
+				Path:      unquote(spec.Path),
+				Line:      spec.Line,
+				Column:    spec.Column,
+				EndColumn: spec.EndColumn,
+			})
+		}
+
+		section := getPkgType(spec.Path, localFlag)
+		groupSection := sections[spec.Group]
+
+		key := specKey(spec.Name, spec.Path)
+		if seen[key] {
+			add(DuplicateImport, "%s is imported more than once", spec.Path)
+		}
+		seen[key] = true
+
+		if spec.Name != "_" && spec.Name != "." {
+			path := unquote(spec.Path)
+			if prev, ok := named[path]; !ok {
+				named[path] = spec
+			} else if prev.Name != spec.Name {
+				add(DuplicateImport, "%s is imported %s and %s at line %d", spec.Path, importName(spec.Name), importName(prev.Name), prev.Line)
+			}
+		}
+
+		if spec.Name != "" && spec.Name != "_" && spec.Name != "." {
+			if prev, ok := names[spec.Name]; !ok {
+				names[spec.Name] = spec
+			} else if prev.Path != spec.Path {
+				add(ConflictingName, "%s is imported as %s, the name is used by %s at line %d", spec.Path, spec.Name, prev.Path, prev.Line)
+			}
+		}
+
+		if section != groupSection {
+			add(WrongSection, "%s belongs in section %q but is in %q", spec.Path, sectionNames[section], sectionNames[groupSection])
+		}
+
+		if i == 0 {
+			continue
+		}
+		prev := specs[i-1]
+		prevSection := getPkgType(prev.Path, localFlag)
+
+		if prev.Group == spec.Group {
+			if prevSection != section {
+				add(MissingBlankLine, "missing blank line between sections %q and %q", sectionNames[prevSection], sectionNames[section])
+			}
+
+			// compare with the closest import of the same section
+			for j := i - 1; j >= 0 && specs[j].Group == spec.Group; j-- {
+				if getPkgType(specs[j].Path, localFlag) != section {
+					continue
+				}
+				if unquote(specs[j].Path) > unquote(spec.Path) {
+					add(WrongOrder, "%s should come before %s", spec.Path, specs[j].Path)
+				}
+				break
+			}
+			continue
+		}
+
+		// the first import of a group is compared with the first one of
+		// the previous group to check the order of sections
+		first := i - 1
+		for first > 0 && specs[first-1].Group == prev.Group {
+			first--
+		}
+		switch prevGroupSection := sections[prev.Group]; {
+		case groupSection < prevGroupSection:
+			add(WrongOrder, "%s should come before %s", spec.Path, specs[first].Path)
+		case groupSection == prevGroupSection && section == groupSection:
+			add(UnexpectedBlankLine, "unexpected blank line inside section %q", sectionNames[section])
+		}
+	}
+	return ret
+}
+
+// Lint returns violations of imports of the file and the fix of its import
+// block, the fix is nil if the file is already formatted
+func Lint(filename string, set *FlagSet) ([]Violation, *Fix, error) {
+	r := formatFile(filename, set, true)
+	if r.Err != nil {
+		return nil, nil, r.Err
+	}
+	if r.Status != StatusChanged {
+		return r.Violations, nil, nil
+	}
+
+	fix, ok := blockFix(r)
+	if !ok {
+		return r.Violations, nil, nil
+	}
+	return r.Violations, &fix, nil
+}
+
+// Fix replaces lines from StartLine up to, but not including, EndLine with Text
+type Fix struct {
+	StartLine, EndLine int
+	Text               string
+}
+
+// blockFix returns the replacement of the content of the import block
+func blockFix(r *Result) (Fix, bool) {
+	style := detectStyle(r.Original)
+	src, res := style.normalize(r.Original), style.normalize(r.Formatted)
+
+	start, end, ok := importBlock(src)
+	if !ok {
+		return Fix{}, false
+	}
+	resStart, resEnd, ok := importBlock(res)
+	if !ok {
+		return Fix{}, false
+	}
+
+	// the byte order mark is not in the import block
+	text := textStyle{crlf: style.crlf}.restore(res[resStart:resEnd])
+	return Fix{
+		StartLine: bytes.Count(src[:start], []byte(linebreak)) + 1,
+		EndLine:   bytes.Count(src[:end], []byte(linebreak)) + 1,
+		Text:      string(text),
+	}, true
+}
+
+func unquote(path string) string {
+	s, err := strconv.Unquote(path)
+	if err != nil {
+		return path
+	}
+	return s
+}
+
+func importName(name string) string {
+	if name == "" {
+		return "without a name"
+	}
+	return "as " + name
+}
@@ -0,0 +1,124 @@
+package gci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc    string
+		imports string
+		//
+		want []Violation
+	}{
+		{
+			desc: "valid",
+			imports: `
+	"fmt"
+	"os"
+
+	"github.com/owner/repo"
+
+	"github.com/local/repo"
+`,
+			want: nil,
+		},
+		{
+			desc: "wrong order",
+			imports: `
+	"os"
+	"fmt"
+`,
+			want: []Violation{
+				{Kind: WrongOrder, Message: `"fmt" should come before "os"`, Path: "fmt", Line: 5, Column: 2, EndColumn: 7},
+			},
+		},
+		{
+			desc: "wrong section and missing blank line",
+			imports: `
+	"fmt"
+	"github.com/owner/repo"
+	"os"
+`,
+			want: []Violation{
+				{Kind: WrongSection, Message: `"github.com/owner/repo" belongs in section "default" but is in "standard"`, Path: "github.com/owner/repo", Line: 5, Column: 2, EndColumn: 25},
+				{Kind: MissingBlankLine, Message: `missing blank line between sections "standard" and "default"`, Path: "github.com/owner/repo", Line: 5, Column: 2, EndColumn: 25},
+				{Kind: MissingBlankLine, Message: `missing blank line between sections "default" and "standard"`, Path: "os", Line: 6, Column: 2, EndColumn: 6},
+			},
+		},
+		{
+			desc: "wrong order of sections",
+			imports: `
+	"github.com/owner/repo"
+
+	"fmt"
+`,
+			want: []Violation{
+				{Kind: WrongOrder, Message: `"fmt" should come before "github.com/owner/repo"`, Path: "fmt", Line: 6, Column: 2, EndColumn: 7},
+			},
+		},
+		{
+			desc: "unexpected blank line",
+			imports: `
+	"fmt"
+
+	"os"
+`,
+			want: []Violation{
+				{Kind: UnexpectedBlankLine, Message: `unexpected blank line inside section "standard"`, Path: "os", Line: 6, Column: 2, EndColumn: 6},
+			},
+		},
+		{
+			desc: "duplicate",
+			imports: `
+	f "fmt"
+	f "fmt"
+`,
+			want: []Violation{
+				{Kind: DuplicateImport, Message: `"fmt" is imported more than once`, Path: "fmt", Line: 5, Column: 2, EndColumn: 9},
+			},
+		},
+		{
+			desc: "blank and dot imports",
+			imports: `
+	"net/http"
+	. "net/http"
+	"unsafe"
+	_ "unsafe"
+`,
+			want: nil,
+		},
+		{
+			desc: "different names",
+			imports: `
+	"net/url"
+	urlpkg "net/url"
+	_ "net/url"
+`,
+			want: []Violation{
+				{Kind: DuplicateImport, Message: `"net/url" is imported as urlpkg and without a name at line 4`, Path: "net/url", Line: 5, Column: 2, EndColumn: 18},
+			},
+		},
+		{
+			desc: "conflicting names",
+			imports: `
+	p "os"
+	p "path"
+`,
+			want: []Violation{
+				{Kind: ConflictingName, Message: `"path" is imported as p, the name is used by "os" at line 4`, Path: "path", Line: 5, Column: 2, EndColumn: 10},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			src := "package a\n\nimport (" + tt.imports + ")\n"
+			require.Equal(t, tt.want, lint([]byte(src), "github.com/local"))
+		})
+	}
+}
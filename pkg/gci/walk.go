@@ -0,0 +1,200 @@
+package gci
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrorList collects errors of all files processed by WalkDir
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, 0, len(l))
+	for _, err := range l {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, linebreak)
+}
+
+// Err returns nil if the list is empty
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// DefaultSkipDirs are patterns of directories that are not walked by default
+var DefaultSkipDirs = []string{"vendor", "testdata", ".*", "_*"}
+
+// walker collects files to process
+type walker struct {
+	root, absRoot string
+	set           *FlagSet
+	// ig and changed are nil if they are disabled
+	ig      *ignorer
+	changed *changeSet
+
+	files []string
+	errs  ErrorList
+}
+
+func newWalker(root string, set *FlagSet) (*walker, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	w := &walker{root: root, absRoot: absRoot, set: set}
+
+	if !set.NoIgnore {
+		if w.ig, err = newIgnorer(root); err != nil {
+			return nil, err
+		}
+	}
+	if set.ChangedSince != "" || set.Staged {
+		if w.changed, err = gitChangedFiles(root, set.ChangedSince, set.Staged); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (w *walker) visitFile(path string, f os.FileInfo, err error) error {
+	if err != nil {
+		// keep walking, the error is reported with the others
+		w.errs = append(w.errs, err)
+		return nil
+	}
+	if f.IsDir() {
+		if w.changed != nil && !w.changed.hasDir(w.abs(path)) {
+			return filepath.SkipDir
+		}
+		// the root is always walked, even if it is "." or "testdata"
+		if path != w.root && (isSkipped(w.root, path, w.set.SkipDirs) || w.ig.isIgnored(path, true)) {
+			return filepath.SkipDir
+		}
+		if err := w.ig.push(path); err != nil {
+			w.errs = append(w.errs, err)
+		}
+		return nil
+	}
+	if w.changed != nil && !w.changed.hasFile(w.abs(path)) {
+		return nil
+	}
+	if isGoFile(f) && !isSkipped(w.root, path, w.set.SkipFiles) && !w.ig.isIgnored(path, false) {
+		w.files = append(w.files, path)
+	}
+	return nil
+}
+
+// abs converts a path passed by filepath.Walk to the absolute one
+func (w *walker) abs(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(w.absRoot, rel)
+}
+
+// isSkipped reports whether the base name or the slash-separated path relative
+// to the root matches any of patterns
+func isSkipped(root, path string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+
+	name := filepath.Base(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func WalkDir(path string, set *FlagSet) error {
+	return walkDir(path, os.Stdout, set)
+}
+
+func walkDir(root string, out io.Writer, set *FlagSet) error {
+	w, err := newWalker(root, set)
+	if err != nil {
+		return err
+	}
+	if err := filepath.Walk(root, w.visitFile); err != nil {
+		w.errs = append(w.errs, err)
+	}
+	errs := append(w.errs, processFiles(w.files, out, set)...)
+	return errs.Err()
+}
+
+type fileOutput struct {
+	result *Result
+	done   chan struct{}
+}
+
+// processFiles formats files concurrently, but reports their results in
+// the same order as files are passed to keep the output deterministic
+func processFiles(files []string, out io.Writer, set *FlagSet) ErrorList {
+	outputs := make([]*fileOutput, len(files))
+	for i := range outputs {
+		outputs[i] = &fileOutput{done: make(chan struct{})}
+	}
+
+	indexes := make(chan int)
+	go func() {
+		for i := range files {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	for i := 0; i < set.jobs(); i++ {
+		go func() {
+			for i := range indexes {
+				o := outputs[i]
+				o.result = processFile(files[i], set)
+				close(o.done)
+			}
+		}()
+	}
+
+	var errs ErrorList
+	for i, o := range outputs {
+		<-o.done
+		if err := set.report(out, o.result); err != nil {
+			errs = append(errs, err)
+		}
+		if o.result.Err != nil {
+			errs = append(errs, o.result.Err)
+		}
+		// release the result as soon as possible
+		outputs[i] = nil
+	}
+	return errs
+}
+
+func (set *FlagSet) jobs() int {
+	if set.Jobs > 0 {
+		return set.Jobs
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+func isGoFile(f os.FileInfo) bool {
+	// ignore non-Go files
+	name := f.Name()
+	return !f.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".go")
+}
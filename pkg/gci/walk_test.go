@@ -0,0 +1,142 @@
+package gci
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkDir(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "gci")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	const src = `package main
+
+import (
+	"github.com/owner/repo"
+	"fmt"
+)
+`
+	const want = `package main
+
+import (
+	"fmt"
+
+	"github.com/owner/repo"
+)
+`
+	require.Nil(os.MkdirAll(filepath.Join(dir, "b"), 0o755))
+	files := []string{"a.go", "b/c.go", "d.go", "e.go", "f.go"}
+	for _, name := range files {
+		require.Nil(ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0o644))
+	}
+
+	newBool := func(v bool) *bool { return &v }
+	flagSet := &FlagSet{
+		DoWrite: newBool(false),
+		DoDiff:  newBool(false),
+		Jobs:    3,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	require.Nil(walkDir(dir, buf, flagSet))
+
+	var expected string
+	for range files {
+		expected += want
+	}
+	require.Equal(expected, buf.String())
+
+	// errors of all files must be collected
+	for _, name := range []string{"x.go", "y.go"} {
+		require.Nil(os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, name)))
+	}
+
+	buf.Reset()
+	err = walkDir(dir, buf, flagSet)
+	require.IsType(ErrorList{}, err)
+	require.Len(err, 2, fmt.Sprint(err))
+	require.Equal(expected, buf.String())
+}
+
+func TestWalkDirSkip(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gci")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	files := []string{
+		"a.go",
+		"a_gen.go",
+		".git/a.go",
+		"_tools/a.go",
+		"vendor/a.go",
+		"pkg/a.go",
+		"pkg/testdata/a.go",
+		"pkg/mod/vendor/a.go",
+	}
+	for _, name := range files {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.Nil(t, ioutil.WriteFile(path, []byte("package a\n"), 0o644))
+	}
+
+	tests := []struct {
+		desc      string
+		root      string
+		skipDirs  []string
+		skipFiles []string
+		//
+		want []string
+	}{
+		{
+			desc:     "defaults",
+			root:     dir,
+			skipDirs: DefaultSkipDirs,
+			want:     []string{"a.go", "a_gen.go", "pkg/a.go"},
+		},
+		{
+			desc:     "skipped root",
+			root:     filepath.Join(dir, "pkg", "testdata"),
+			skipDirs: DefaultSkipDirs,
+			want:     []string{"pkg/testdata/a.go"},
+		},
+		{
+			desc:      "custom patterns",
+			root:      dir,
+			skipDirs:  []string{"pkg/*"},
+			skipFiles: []string{"*_gen.go"},
+			want:      []string{".git/a.go", "_tools/a.go", "a.go", "pkg/a.go", "vendor/a.go"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.desc, func(t *testing.T) {
+			set := &FlagSet{SkipDirs: tt.skipDirs, SkipFiles: tt.skipFiles, NoIgnore: true}
+			w, err := newWalker(tt.root, set)
+			require.Nil(t, err)
+			require.Nil(t, filepath.Walk(tt.root, w.visitFile))
+			require.Nil(t, w.errs.Err())
+
+			got := w.files
+
+			for i := range got {
+				rel, err := filepath.Rel(dir, got[i])
+				require.Nil(t, err)
+				got[i] = filepath.ToSlash(rel)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
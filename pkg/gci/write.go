@@ -0,0 +1,71 @@
+package gci
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// writeFile replaces the file atomically: data is written to a temporary file
+// in the same directory, which is renamed over the original one after it is
+// synced, so the original file is never left truncated
+func writeFile(filename string, data []byte, set *FlagSet) (err error) {
+	fi, err := os.Lstat(filename)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if !set.FollowSymlinks {
+			return fmt.Errorf("refusing to write %s since it is a symlink", filename)
+		}
+		if filename, err = filepath.EvalSymlinks(filename); err != nil {
+			return err
+		}
+		if fi, err = os.Stat(filename); err != nil {
+			return err
+		}
+	}
+
+	dir, name := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	// the temporary file is hidden, so it is skipped by WalkDir
+	tmp, err := ioutil.TempFile(dir, "."+name+".gci-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	// On Windows, we need to re-set the permissions from the file. See golang/go#38225.
+	if err = os.Chmod(tmp.Name(), fi.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return err
+	}
+	if err = chown(tmp.Name(), fi); err != nil {
+		return err
+	}
+	if set.PreserveMtime {
+		if err = os.Chtimes(tmp.Name(), time.Now(), fi.ModTime()); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
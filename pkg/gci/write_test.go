@@ -0,0 +1,77 @@
+package gci
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "gci")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "a.go")
+	link := filepath.Join(dir, "link.go")
+	require.Nil(t, ioutil.WriteFile(filename, []byte("old"), 0o600))
+	require.Nil(t, os.Symlink(filename, link))
+
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.Nil(t, os.Chtimes(filename, mtime, mtime))
+
+	requireFiles := func(t *testing.T, want ...string) {
+		infos, err := ioutil.ReadDir(dir)
+		require.Nil(t, err)
+
+		var names []string
+		for _, fi := range infos {
+			names = append(names, fi.Name())
+		}
+		require.Equal(t, want, names)
+	}
+
+	t.Run("preserve mode and mtime", func(t *testing.T) {
+		require.Nil(t, writeFile(filename, []byte("new"), &FlagSet{PreserveMtime: true}))
+
+		data, err := ioutil.ReadFile(filename)
+		require.Nil(t, err)
+		require.Equal(t, "new", string(data))
+
+		fi, err := os.Stat(filename)
+		require.Nil(t, err)
+		require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+		require.True(t, mtime.Equal(fi.ModTime()))
+
+		requireFiles(t, "a.go", "link.go")
+	})
+
+	t.Run("refuse symlinks", func(t *testing.T) {
+		require.NotNil(t, writeFile(link, []byte("link"), &FlagSet{}))
+
+		data, err := ioutil.ReadFile(filename)
+		require.Nil(t, err)
+		require.Equal(t, "new", string(data))
+
+		requireFiles(t, "a.go", "link.go")
+	})
+
+	t.Run("follow symlinks", func(t *testing.T) {
+		require.Nil(t, writeFile(link, []byte("link"), &FlagSet{FollowSymlinks: true}))
+
+		data, err := ioutil.ReadFile(filename)
+		require.Nil(t, err)
+		require.Equal(t, "link", string(data))
+
+		fi, err := os.Lstat(link)
+		require.Nil(t, err)
+		require.NotZero(t, fi.Mode()&os.ModeSymlink)
+
+		requireFiles(t, "a.go", "link.go")
+	})
+}
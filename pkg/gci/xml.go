@@ -0,0 +1,150 @@
+package gci
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+type checkstyleOutput struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// checkstyleReporter prints violations of all files in the checkstyle format
+type checkstyleReporter struct {
+	output checkstyleOutput
+}
+
+func (c *checkstyleReporter) Report(_ io.Writer, r *Result) error {
+	if len(r.Violations) == 0 {
+		return nil
+	}
+
+	file := checkstyleFile{Name: filepath.ToSlash(r.Filename)}
+	for _, v := range r.Violations {
+		file.Errors = append(file.Errors, checkstyleError{
+			Line:     v.Line,
+			Column:   v.Column,
+			Severity: "error",
+			Message:  v.Message,
+			Source:   "gci." + string(v.Kind),
+		})
+	}
+	c.output.Files = append(c.output.Files, file)
+	return nil
+}
+
+func (c *checkstyleReporter) Finish(out io.Writer) error {
+	c.output.Version = "5.0"
+	return writeXML(out, c.output)
+}
+
+func (c *checkstyleReporter) Needs() (diff, violations, moves bool) {
+	return false, true, false
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",cdata"`
+}
+
+// junitReporter prints a test case for every file, changed files are failed
+// test cases with the diff and violations
+type junitReporter struct {
+	suite junitTestSuite
+}
+
+func (j *junitReporter) Report(_ io.Writer, r *Result) error {
+	tc := junitTestCase{
+		Name:      filepath.ToSlash(r.Filename),
+		ClassName: "gci",
+	}
+
+	switch r.Status {
+	case StatusChanged:
+		j.suite.Failures++
+
+		var text strings.Builder
+		for _, v := range r.Violations {
+			fmt.Fprintf(&text, "%s:%d:%d: %s\n", tc.Name, v.Line, v.Column, v.Message)
+		}
+		if len(r.Violations) > 0 {
+			text.WriteString(linebreak)
+		}
+		text.Write(r.Diff)
+
+		tc.Failure = &junitMessage{Message: "imports are not formatted", Type: "gci", Text: text.String()}
+	case StatusError:
+		j.suite.Errors++
+		tc.Error = &junitMessage{Message: r.Err.Error()}
+	case StatusSkipped:
+		j.suite.Skipped++
+		tc.Skipped = &junitMessage{Message: r.Reason}
+	}
+
+	j.suite.Tests++
+	j.suite.TestCases = append(j.suite.TestCases, tc)
+	return nil
+}
+
+func (j *junitReporter) Finish(out io.Writer) error {
+	j.suite.Name = "gci"
+	return writeXML(out, junitTestSuites{Suites: []junitTestSuite{j.suite}})
+}
+
+func (j *junitReporter) Needs() (diff, violations, moves bool) {
+	return true, true, false
+}
+
+func writeXML(out io.Writer, v interface{}) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	data = append([]byte(xml.Header), data...)
+	if _, err := out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write: %v", err)
+	}
+	return nil
+}